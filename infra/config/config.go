@@ -25,20 +25,107 @@ type RedisConfig struct {
 
 type JWTConfig struct {
 	Secret            string
-	ExpireTime        int64 // access_token 过期时间（秒）
-	RefreshExpireTime int64 // refresh_token 过期时间（秒）
+	ExpireTime        int64          `mapstructure:"expire_time"`         // access_token 过期时间（秒）
+	RefreshExpireTime int64          `mapstructure:"refresh_expire_time"` // refresh_token 过期时间（秒）
+	ActiveKey         string         `mapstructure:"active_key"`          // 当前签名密钥 kid，为空时使用 Secret 进行 HS256 签名
+	Keys              []JWTKeyConfig // 签名密钥，非 ActiveKey 的密钥仅用于验证（轮换后退役的密钥）
+	Issuer            string         // 签发方 iss，配置后仅接受该签发方的 token
+	Audience          []string       // 受众 aud，签发时全部写入，验证时 token 须包含其中之一
+	Leeway            int64          // 校验 exp/nbf 时允许的时钟偏差（秒）
+
+	// 配置 ActiveKey 后 Secret 默认不再用于验证；迁移期间可设置截止时间（RFC 3339），此前仍接受 Secret 签发的无 kid token
+	AcceptLegacySecretUntil string `mapstructure:"accept_legacy_secret_until"`
+}
+
+type JWTKeyConfig struct {
+	ID         string // kid
+	Algorithm  string // RS256, ES256, EdDSA, HS256
+	PrivateKey string `mapstructure:"private_key"` // 私钥 PEM 文件路径，退役密钥可不配置
+	PublicKey  string `mapstructure:"public_key"`  // 公钥 PEM 文件路径，为空时从私钥推导
+	Secret     string // HS256 密钥
+}
+
+type LoginConfig struct {
+	MaxAttempts   int64 `mapstructure:"max_attempts"`    // 同一用户名连续失败次数上限，达到后锁定，默认 5
+	MaxIPAttempts int64 `mapstructure:"max_ip_attempts"` // 同一 IP 失败次数上限，达到后锁定，默认 20
+	LockDuration  int64 `mapstructure:"lock_duration"`   // 锁定时长及失败计数窗口（秒），默认 900
+	DelayStep     int64 `mapstructure:"delay_step"`      // 每次失败递增的响应延迟（毫秒），默认 200
+	MaxDelay      int64 `mapstructure:"max_delay"`       // 最大响应延迟（毫秒），默认 3000
+}
+
+type PasswordConfig struct {
+	MinLength        int    `mapstructure:"min_length"`         // 最小长度，默认 6
+	MaxLength        int    `mapstructure:"max_length"`         // 最大长度（字节），默认 72
+	RequireUpper     bool   `mapstructure:"require_upper"`      // 必须包含大写字母
+	RequireLower     bool   `mapstructure:"require_lower"`      // 必须包含小写字母
+	RequireDigit     bool   `mapstructure:"require_digit"`      // 必须包含数字
+	RequireSymbol    bool   `mapstructure:"require_symbol"`     // 必须包含特殊字符
+	DisallowUserInfo bool   `mapstructure:"disallow_user_info"` // 不允许包含用户名或邮箱前缀
+	BannedFile       string `mapstructure:"banned_file"`        // 禁用密码列表文件，每行一个，不区分大小写
+	HistorySize      int    `mapstructure:"history_size"`       // 不允许与最近 N 次使用过的密码相同，0 表示不限制
+
+	Algorithm  string       // 新密码使用的哈希算法：argon2id（默认）, bcrypt
+	BcryptCost int          `mapstructure:"bcrypt_cost"` // bcrypt 计算成本，默认 10
+	Argon2     Argon2Config // argon2id 参数
+}
+
+type Argon2Config struct {
+	Memory      uint32 // 内存（KiB），默认 65536
+	Iterations  uint32 // 迭代次数，默认 3
+	Parallelism uint8  // 并行度，默认 2
+	SaltLength  uint32 `mapstructure:"salt_length"` // 盐长度（字节），默认 16
+	KeyLength   uint32 `mapstructure:"key_length"`  // 输出长度（字节），默认 32
+}
+
+type RegisterConfig struct {
+	VerifyEmail bool `mapstructure:"verify_email"` // 注册后需验证邮箱才能登录
+}
+
+type TwoFactorConfig struct {
+	Issuer string // otpauth URI 中显示的发行方名称，默认 go-tpl
+}
+
+type OIDCConfig struct {
+	Providers []OIDCProviderConfig
+}
+
+type OIDCProviderConfig struct {
+	Name          string   // 登录方式标识，对应路由 /api/oidc/:provider
+	Issuer        string   // 通过 {issuer}/.well-known/openid-configuration 发现端点
+	ClientID      string   `mapstructure:"client_id"`
+	ClientSecret  string   `mapstructure:"client_secret"`
+	RedirectURL   string   `mapstructure:"redirect_url"` // 前端回调页面地址，需在 IdP 中登记
+	Scopes        []string // 默认 openid email profile
+	AutoProvision bool     `mapstructure:"auto_provision"` // 邮箱未匹配到本地用户时自动创建
+}
+
+type MailConfig struct {
+	Driver   string // smtp（默认）, log（仅用于开发测试，邮件中的重置链接等凭证会写入文件或日志）
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	File     string // log 驱动的输出文件，为空时输出到日志
 }
 
 type ServerConfig struct {
-	Port int
-	Mode string // debug, release, test
+	Port      int
+	Mode      string // debug, release, test
+	PublicURL string `mapstructure:"public_url"` // 前端访问地址，用于生成邮件中的链接
 }
 
 type Config struct {
-	Database DBConfig
-	Redis    RedisConfig
-	JWT      JWTConfig
-	Server   ServerConfig
+	Database  DBConfig
+	Redis     RedisConfig
+	JWT       JWTConfig
+	Login     LoginConfig
+	Password  PasswordConfig
+	Register  RegisterConfig
+	TwoFactor TwoFactorConfig `mapstructure:"two_factor"`
+	OIDC      OIDCConfig
+	Mail      MailConfig
+	Server    ServerConfig
 }
 
 func Load() (*Config, error) {
@@ -0,0 +1,469 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"unicode/utf8"
+)
+
+// Env 求值环境，顶层变量名到值的映射。
+// 支持的值类型：nil、bool、数字（统一按 float64 处理）、string、[]any、[]string、map[string]any、map[string]string
+type Env map[string]any
+
+// Eval 对表达式求值，引用的变量不存在时为 null
+func (p *Program) Eval(env Env) (any, error) {
+	return p.root.eval(env)
+}
+
+// EvalBool 对表达式求值，结果须为布尔值
+func (p *Program) EvalBool(env Env) (bool, error) {
+	v, err := p.Eval(env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, evalError("expression result is %s, not bool", typeName(v))
+	}
+	return b, nil
+}
+
+func evalError(format string, args ...any) error {
+	return &Error{Pos: -1, Msg: fmt.Sprintf(format, args...)}
+}
+
+// ==================== 语法树 ====================
+
+type node interface {
+	eval(env Env) (any, error)
+}
+
+type literal struct {
+	val any
+}
+
+func (n *literal) eval(Env) (any, error) {
+	return n.val, nil
+}
+
+type ident struct {
+	name string
+}
+
+func (n *ident) eval(env Env) (any, error) {
+	return normalize(env[n.name])
+}
+
+// member 成员访问：对象取字段、列表取下标，对 null 取成员结果为 null
+type member struct {
+	obj node
+	key node
+}
+
+func (n *member) eval(env Env) (any, error) {
+	obj, err := n.obj.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	key, err := n.key.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch o := obj.(type) {
+	case nil:
+		return nil, nil
+	case map[string]any:
+		k, ok := key.(string)
+		if !ok {
+			return nil, evalError("object key must be string, got %s", typeName(key))
+		}
+		return normalize(o[k])
+	case []any:
+		i, ok := key.(float64)
+		if !ok || i != math.Trunc(i) {
+			return nil, evalError("list index must be integer, got %s", typeName(key))
+		}
+		// 先以浮点数比较边界，超出 int 范围的下标转换后会溢出
+		if i < 0 || i >= float64(len(o)) {
+			return nil, nil
+		}
+		return normalize(o[int(i)])
+	default:
+		return nil, evalError("cannot access member of %s", typeName(obj))
+	}
+}
+
+type list struct {
+	items []node
+}
+
+func (n *list) eval(env Env) (any, error) {
+	values := make([]any, len(n.items))
+	for i, item := range n.items {
+		v, err := item.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+type unary struct {
+	op string
+	x  node
+}
+
+func (n *unary) eval(env Env) (any, error) {
+	v, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "!":
+		b, ok := v.(bool)
+		if !ok {
+			return nil, evalError("operator ! requires bool, got %s", typeName(v))
+		}
+		return !b, nil
+	default:
+		f, ok := v.(float64)
+		if !ok {
+			return nil, evalError("operator - requires number, got %s", typeName(v))
+		}
+		return -f, nil
+	}
+}
+
+// logical && 和 ||，短路求值，操作数须为布尔值
+type logical struct {
+	and  bool
+	l, r node
+}
+
+func (n *logical) eval(env Env) (any, error) {
+	l, err := evalBool(n.l, env)
+	if err != nil {
+		return nil, err
+	}
+	if l != n.and {
+		return l, nil
+	}
+	return evalBool(n.r, env)
+}
+
+func evalBool(x node, env Env) (bool, error) {
+	v, err := x.eval(env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, evalError("logical operator requires bool, got %s", typeName(v))
+	}
+	return b, nil
+}
+
+type binary struct {
+	op   string
+	l, r node
+}
+
+func (n *binary) eval(env Env) (any, error) {
+	l, err := n.l.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	r, err := n.r.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(l, r)
+	case "!=":
+		eq, err := equal(l, r)
+		if err != nil {
+			return nil, err
+		}
+		return !eq, nil
+	case "<", "<=", ">", ">=":
+		return compare(n.op, l, r)
+	case "in":
+		return contains(r, l)
+	case "+":
+		if ls, ok := l.(string); ok {
+			if rs, ok := r.(string); ok {
+				return ls + rs, nil
+			}
+		}
+	}
+
+	lf, lok := l.(float64)
+	rf, rok := r.(float64)
+	if !lok || !rok {
+		return nil, evalError("operator %s requires numbers, got %s and %s", n.op, typeName(l), typeName(r))
+	}
+	switch n.op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/":
+		if rf == 0 {
+			return nil, evalError("division by zero")
+		}
+		return lf / rf, nil
+	default:
+		if rf == 0 {
+			return nil, evalError("division by zero")
+		}
+		return math.Mod(lf, rf), nil
+	}
+}
+
+type call struct {
+	name string
+	fn   func(args []any) (any, error)
+	args []node
+}
+
+func (n *call) eval(env Env) (any, error) {
+	args := make([]any, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return n.fn(args)
+}
+
+// ==================== 运算 ====================
+
+// equal 比较标量是否相等，类型不同时不相等；列表和对象不可比较
+func equal(l, r any) (bool, error) {
+	switch l.(type) {
+	case []any, map[string]any:
+		return false, evalError("cannot compare %s", typeName(l))
+	}
+	switch r.(type) {
+	case []any, map[string]any:
+		return false, evalError("cannot compare %s", typeName(r))
+	}
+	return l == r, nil
+}
+
+// compare 比较大小，操作数须同为数字或同为字符串
+func compare(op string, l, r any) (bool, error) {
+	var c int
+	switch lv := l.(type) {
+	case float64:
+		rv, ok := r.(float64)
+		if !ok {
+			return false, evalError("cannot compare %s with %s", typeName(l), typeName(r))
+		}
+		switch {
+		case lv < rv:
+			c = -1
+		case lv > rv:
+			c = 1
+		}
+	case string:
+		rv, ok := r.(string)
+		if !ok {
+			return false, evalError("cannot compare %s with %s", typeName(l), typeName(r))
+		}
+		c = strings.Compare(lv, rv)
+	default:
+		return false, evalError("cannot compare %s with %s", typeName(l), typeName(r))
+	}
+
+	switch op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	default:
+		return c >= 0, nil
+	}
+}
+
+// contains in 运算：列表包含元素、字符串包含子串、对象包含键，对 null 结果为 false
+func contains(container, x any) (bool, error) {
+	switch c := container.(type) {
+	case nil:
+		return false, nil
+	case []any:
+		for _, item := range c {
+			item, err := normalize(item)
+			if err != nil {
+				return false, err
+			}
+			if eq, err := equal(x, item); err == nil && eq {
+				return true, nil
+			}
+		}
+		return false, nil
+	case string:
+		s, ok := x.(string)
+		if !ok {
+			return false, evalError("left operand of in must be string, got %s", typeName(x))
+		}
+		return strings.Contains(c, s), nil
+	case map[string]any:
+		s, ok := x.(string)
+		if !ok {
+			return false, evalError("left operand of in must be string, got %s", typeName(x))
+		}
+		_, exists := c[s]
+		return exists, nil
+	default:
+		return false, evalError("right operand of in must be list, string or object, got %s", typeName(container))
+	}
+}
+
+// normalize 将环境中的值转换为表达式内部类型，数字统一为 float64
+func normalize(v any) (any, error) {
+	switch x := v.(type) {
+	case nil, bool, string, float64, []any, map[string]any:
+		return x, nil
+	case int:
+		return float64(x), nil
+	case int8:
+		return float64(x), nil
+	case int16:
+		return float64(x), nil
+	case int32:
+		return float64(x), nil
+	case int64:
+		return float64(x), nil
+	case uint:
+		return float64(x), nil
+	case uint8:
+		return float64(x), nil
+	case uint16:
+		return float64(x), nil
+	case uint32:
+		return float64(x), nil
+	case uint64:
+		return float64(x), nil
+	case float32:
+		return float64(x), nil
+	case []string:
+		items := make([]any, len(x))
+		for i, s := range x {
+			items[i] = s
+		}
+		return items, nil
+	case map[string]string:
+		m := make(map[string]any, len(x))
+		for k, s := range x {
+			m[k] = s
+		}
+		return m, nil
+	default:
+		return nil, evalError("unsupported value type %T", v)
+	}
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "list"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// ==================== 函数 ====================
+
+type function struct {
+	arity int
+	call  func(args []any) (any, error)
+}
+
+// functions 可调用的函数白名单，均无副作用
+var functions = map[string]function{
+	"len": {1, func(args []any) (any, error) {
+		switch v := args[0].(type) {
+		case string:
+			return float64(utf8.RuneCountInString(v)), nil
+		case []any:
+			return float64(len(v)), nil
+		case map[string]any:
+			return float64(len(v)), nil
+		case nil:
+			return float64(0), nil
+		}
+		return nil, evalError("len requires string, list or object, got %s", typeName(args[0]))
+	}},
+	"lower": {1, stringFunc(strings.ToLower)},
+	"upper": {1, stringFunc(strings.ToUpper)},
+	"startsWith": {2, func(args []any) (any, error) {
+		s, prefix, err := twoStrings("startsWith", args)
+		if err != nil {
+			return nil, err
+		}
+		return strings.HasPrefix(s, prefix), nil
+	}},
+	"endsWith": {2, func(args []any) (any, error) {
+		s, suffix, err := twoStrings("endsWith", args)
+		if err != nil {
+			return nil, err
+		}
+		return strings.HasSuffix(s, suffix), nil
+	}},
+	// cidr(ip, network) 判断 IP 是否属于网段，IP 无法解析时为 false
+	"cidr": {2, func(args []any) (any, error) {
+		ip, network, err := twoStrings("cidr", args)
+		if err != nil {
+			return nil, err
+		}
+		_, ipNet, err := net.ParseCIDR(network)
+		if err != nil {
+			return nil, evalError("invalid network %q", network)
+		}
+		parsed := net.ParseIP(ip)
+		return parsed != nil && ipNet.Contains(parsed), nil
+	}},
+}
+
+func stringFunc(fn func(string) string) func(args []any) (any, error) {
+	return func(args []any) (any, error) {
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, evalError("string argument required, got %s", typeName(args[0]))
+		}
+		return fn(s), nil
+	}
+}
+
+func twoStrings(name string, args []any) (string, string, error) {
+	a, ok1 := args[0].(string)
+	b, ok2 := args[1].(string)
+	if !ok1 || !ok2 {
+		return "", "", evalError("%s requires string arguments, got %s and %s", name, typeName(args[0]), typeName(args[1]))
+	}
+	return a, b, nil
+}
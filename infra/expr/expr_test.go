@@ -0,0 +1,199 @@
+package expr
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEval(t *testing.T) {
+	env := Env{
+		"ip":   "10.1.2.3",
+		"time": map[string]any{"hour": 9, "weekday": 1},
+		"user": map[string]any{"id": uint(7), "dept_id": float64(3), "username": "Alice", "tags": []string{"ops", "dev"}},
+		"resource": map[string]any{
+			"owner_id": 7,
+			"labels":   map[string]string{"env": "prod"},
+		},
+	}
+
+	cases := []struct {
+		src  string
+		want any
+	}{
+		{"1 + 2 * 3", float64(7)},
+		{"(1 + 2) * 3", float64(9)},
+		{"-2 - -3", float64(1)},
+		{"7 % 4", float64(3)},
+		{"'a' + \"b\"", "ab"},
+		{"user.id == resource.owner_id", true},
+		{"user['dept_id'] in [1, 2, 3]", true},
+		{"time.hour >= 9 && time.hour < 18", true},
+		{"time.weekday in [0, 6]", false},
+		{"'ops' in user.tags", true},
+		{"'env' in resource.labels", true},
+		{"resource.labels.env == 'prod'", true},
+		{"'li' in user.username", true},
+		{"lower(user.username) == 'alice'", true},
+		{"upper('a') + 'b'", "Ab"},
+		{"len(user.tags)", float64(2)},
+		{"user.tags[1]", "dev"},
+		{"user.tags[5]", nil},
+		{"user.tags[-1]", nil},
+		{"[1][10000000000000000000]", nil},
+		{"[1][-10000000000000000000]", nil},
+		{"startsWith(ip, '10.') && endsWith(ip, '.3')", true},
+		{"cidr(ip, '10.0.0.0/8')", true},
+		{"cidr(ip, '192.168.0.0/16')", false},
+		{"cidr('bad', '10.0.0.0/8')", false},
+		{"missing == null", true},
+		{"missing.field.deep", nil},
+		{"missing in [1]", false},
+		{"1 in missing", false},
+		{"1 == '1'", false},
+		{"'a' < 'b'", true},
+		{"!(1 > 2)", true},
+		{"false && missing.x > 1", false},
+		{"true || 1", true},
+	}
+	for _, c := range cases {
+		p, err := Compile(c.src)
+		require.NoError(t, err, c.src)
+		got, err := p.Eval(env)
+		require.NoError(t, err, c.src)
+		assert.Equal(t, c.want, got, c.src)
+	}
+}
+
+func TestEvalError(t *testing.T) {
+	cases := []string{
+		"1 / 0",
+		"1 % 0",
+		"1 + 'a'",
+		"!1",
+		"-'a'",
+		"1 && true",
+		"true && 1",
+		"1 < 'a'",
+		"[1] == [1]",
+		"'a'.b",
+		"[1]['a']",
+		"cidr('10.0.0.1', 'bad')",
+		"len(1)",
+		"obj.x",
+	}
+	for _, src := range cases {
+		p, err := Compile(src)
+		require.NoError(t, err, src)
+		_, err = p.Eval(Env{"obj": struct{}{}})
+		var e *Error
+		require.ErrorAs(t, err, &e, src)
+		assert.Equal(t, -1, e.Pos, src)
+	}
+}
+
+func TestEvalBool(t *testing.T) {
+	p, err := Compile("user.id == 1")
+	require.NoError(t, err)
+
+	ok, err := p.EvalBool(Env{"user": map[string]any{"id": 1}})
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = p.EvalBool(nil)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	p, err = Compile("user.id")
+	require.NoError(t, err)
+	_, err = p.EvalBool(Env{"user": map[string]any{"id": 1}})
+	assert.Error(t, err)
+}
+
+func TestCompileError(t *testing.T) {
+	cases := []struct {
+		src string
+		pos int
+	}{
+		{"", 0},
+		{"   ", 0},
+		{"1 +", 3},
+		{"(1", 2},
+		{"a ==", 4},
+		{"a == b == c", 7},
+		{"'abc", 0},
+		{"'a\\x'", 2},
+		{"1.", 0},
+		{"a & b", 2},
+		{"a = 1", 2},
+		{"a.1", 2},
+		{"foo(1)", 0},
+		{"len(1, 2)", 0},
+		{"in", 0},
+		{"[1, 2", 5},
+		{"a b", 2},
+	}
+	for _, c := range cases {
+		_, err := Compile(c.src)
+		var e *Error
+		require.ErrorAs(t, err, &e, c.src)
+		assert.Equal(t, c.pos, e.Pos, c.src)
+	}
+}
+
+func TestCompileLimits(t *testing.T) {
+	_, err := Compile(strings.Repeat("1", MaxLength+1))
+	assert.Error(t, err)
+
+	_, err = Compile(strings.Repeat("(", 100) + "1" + strings.Repeat(")", 100))
+	assert.ErrorContains(t, err, "nested too deeply")
+
+	_, err = Compile(strings.Repeat("!", 100) + "true")
+	assert.ErrorContains(t, err, "nested too deeply")
+
+	_, err = Compile(strings.Repeat("(", 10) + "1" + strings.Repeat(")", 10))
+	assert.NoError(t, err)
+}
+
+func TestVars(t *testing.T) {
+	p, err := Compile("user.id == resource.owner_id || cidr(ip, '10.0.0.0/8') || user.admin")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ip", "resource", "user"}, p.Vars())
+	assert.Equal(t, "user.id == resource.owner_id || cidr(ip, '10.0.0.0/8') || user.admin", p.String())
+
+	p, err = Compile("len('abc') == 3")
+	require.NoError(t, err)
+	assert.Empty(t, p.Vars())
+}
+
+// FuzzEval 任意输入编译和求值都只能返回错误，不能 panic
+func FuzzEval(f *testing.F) {
+	seeds := []string{
+		"user.id == resource.owner_id",
+		"[1, 2][1] in [2]",
+		"[1][10000000000000000000]",
+		"cidr(ip, '10.0.0.0/8') && time.hour >= 9",
+		"len(user.tags) > 1 || startsWith(lower(user.name), 'a')",
+		"-(1 / 0) % 2",
+		"resource['labels'].env + 'x'",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	env := Env{
+		"ip":       "10.1.2.3",
+		"time":     map[string]any{"hour": 9},
+		"user":     map[string]any{"id": 7, "name": "Alice", "tags": []string{"ops"}},
+		"resource": map[string]any{"owner_id": 7, "labels": map[string]string{"env": "prod"}},
+	}
+	f.Fuzz(func(t *testing.T, src string) {
+		p, err := Compile(src)
+		if err != nil {
+			return
+		}
+		_, _ = p.Eval(env)
+	})
+}
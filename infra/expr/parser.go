@@ -0,0 +1,488 @@
+package expr
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	MaxLength = 1024 // 表达式最大长度（字节）
+	maxDepth  = 32   // 最大嵌套深度，防止恶意输入耗尽栈空间
+)
+
+// Error 表达式编译或求值错误，Pos 为出错位置（字节偏移），求值错误为 -1
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	if e.Pos < 0 {
+		return e.Msg
+	}
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Pos)
+}
+
+// Program 编译后的表达式，可并发求值
+type Program struct {
+	src  string
+	root node
+	vars []string
+}
+
+// Compile 编译表达式。语法：
+//   - 字面量：数字、'字符串' 或 "字符串"、true、false、null、[列表]
+//   - 变量与成员访问：ip、user.dept_id、resource["owner_id"]
+//   - 运算符（优先级由低到高）：||；&&；== != < <= > >= in；+ -；* / %；! -（一元）
+//   - 函数：len、lower、upper、startsWith、endsWith、cidr
+func Compile(src string) (*Program, error) {
+	if len(src) > MaxLength {
+		return nil, &Error{Pos: MaxLength, Msg: "expression too long"}
+	}
+
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	if tokens[0].kind == tokEOF {
+		return nil, &Error{Pos: 0, Msg: "empty expression"}
+	}
+
+	p := &parser{tokens: tokens, vars: make(map[string]bool)}
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("unexpected %q", tok.text)}
+	}
+
+	vars := make([]string, 0, len(p.vars))
+	for name := range p.vars {
+		vars = append(vars, name)
+	}
+	slices.Sort(vars)
+	return &Program{src: src, root: root, vars: vars}, nil
+}
+
+// String 返回表达式源码
+func (p *Program) String() string {
+	return p.src
+}
+
+// Vars 返回表达式引用的顶层变量名，调用方可据此按需准备求值环境
+func (p *Program) Vars() []string {
+	return p.vars
+}
+
+// ==================== 词法分析 ====================
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string // 运算符、标识符原文；字符串为转义后的内容
+	num  float64
+	pos  int
+}
+
+var twoCharOps = []string{"==", "!=", "<=", ">=", "&&", "||"}
+
+func lex(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case isDigit(c):
+			start := i
+			for i < len(src) && isDigit(src[i]) {
+				i++
+			}
+			if i < len(src) && src[i] == '.' {
+				i++
+				if i >= len(src) || !isDigit(src[i]) {
+					return nil, &Error{Pos: start, Msg: "invalid number"}
+				}
+				for i < len(src) && isDigit(src[i]) {
+					i++
+				}
+			}
+			num, err := strconv.ParseFloat(src[start:i], 64)
+			if err != nil {
+				return nil, &Error{Pos: start, Msg: "invalid number"}
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: src[start:i], num: num, pos: start})
+
+		case c == '\'' || c == '"':
+			start := i
+			var b strings.Builder
+			i++
+			for {
+				if i >= len(src) {
+					return nil, &Error{Pos: start, Msg: "unterminated string"}
+				}
+				if src[i] == c {
+					i++
+					break
+				}
+				if src[i] == '\\' {
+					if i+1 >= len(src) {
+						return nil, &Error{Pos: start, Msg: "unterminated string"}
+					}
+					switch src[i+1] {
+					case '\\', '\'', '"':
+						b.WriteByte(src[i+1])
+					case 'n':
+						b.WriteByte('\n')
+					case 't':
+						b.WriteByte('\t')
+					default:
+						return nil, &Error{Pos: i, Msg: "invalid escape sequence"}
+					}
+					i += 2
+					continue
+				}
+				b.WriteByte(src[i])
+				i++
+			}
+			tokens = append(tokens, token{kind: tokString, text: b.String(), pos: start})
+
+		case isIdentStart(c):
+			start := i
+			for i < len(src) && (isIdentStart(src[i]) || isDigit(src[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: src[start:i], pos: start})
+
+		default:
+			if i+1 < len(src) && slices.Contains(twoCharOps, src[i:i+2]) {
+				tokens = append(tokens, token{kind: tokOp, text: src[i : i+2], pos: i})
+				i += 2
+				continue
+			}
+			if strings.IndexByte("<>!+-*/%()[],.", c) < 0 {
+				return nil, &Error{Pos: i, Msg: fmt.Sprintf("unexpected character %q", c)}
+			}
+			tokens = append(tokens, token{kind: tokOp, text: string(c), pos: i})
+			i++
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// ==================== 语法分析 ====================
+
+type parser struct {
+	tokens []token
+	cur    int
+	depth  int
+	vars   map[string]bool
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.cur]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.cur]
+	if tok.kind != tokEOF {
+		p.cur++
+	}
+	return tok
+}
+
+// accept 当前为指定运算符时消费并返回 true
+func (p *parser) accept(op string) bool {
+	if tok := p.peek(); tok.kind == tokOp && tok.text == op {
+		p.cur++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.accept(op) {
+		tok := p.peek()
+		return &Error{Pos: tok.pos, Msg: fmt.Sprintf("expected %q", op)}
+	}
+	return nil
+}
+
+func (p *parser) enter() error {
+	p.depth++
+	if p.depth > maxDepth {
+		return &Error{Pos: p.peek().pos, Msg: "expression nested too deeply"}
+	}
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+// parseExpr or := and ("||" and)*
+func (p *parser) parseExpr() (node, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{and: false, l: left, r: right}
+	}
+	return left, nil
+}
+
+// parseAnd and := cmp ("&&" cmp)*
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseCmp()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseCmp()
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{and: true, l: left, r: right}
+	}
+	return left, nil
+}
+
+// parseCmp cmp := add (("==" | "!=" | "<" | "<=" | ">" | ">=" | "in") add)?，比较运算不可连用
+func (p *parser) parseCmp() (node, error) {
+	left, err := p.parseAdd()
+	if err != nil {
+		return nil, err
+	}
+
+	tok := p.peek()
+	var op string
+	switch {
+	case tok.kind == tokOp && slices.Contains([]string{"==", "!=", "<", "<=", ">", ">="}, tok.text):
+		op = tok.text
+	case tok.kind == tokIdent && tok.text == "in":
+		op = "in"
+	default:
+		return left, nil
+	}
+	p.next()
+
+	right, err := p.parseAdd()
+	if err != nil {
+		return nil, err
+	}
+	return &binary{op: op, l: left, r: right}, nil
+}
+
+// parseAdd add := mul (("+" | "-") mul)*
+func (p *parser) parseAdd() (node, error) {
+	left, err := p.parseMul()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op string
+		switch {
+		case p.accept("+"):
+			op = "+"
+		case p.accept("-"):
+			op = "-"
+		default:
+			return left, nil
+		}
+		right, err := p.parseMul()
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{op: op, l: left, r: right}
+	}
+}
+
+// parseMul mul := unary (("*" | "/" | "%") unary)*
+func (p *parser) parseMul() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op string
+		switch {
+		case p.accept("*"):
+			op = "*"
+		case p.accept("/"):
+			op = "/"
+		case p.accept("%"):
+			op = "%"
+		default:
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{op: op, l: left, r: right}
+	}
+}
+
+// parseUnary unary := ("!" | "-") unary | postfix
+func (p *parser) parseUnary() (node, error) {
+	for _, op := range []string{"!", "-"} {
+		if p.accept(op) {
+			if err := p.enter(); err != nil {
+				return nil, err
+			}
+			defer p.leave()
+
+			x, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			return &unary{op: op, x: x}, nil
+		}
+	}
+	return p.parsePostfix()
+}
+
+// parsePostfix postfix := primary ("." ident | "[" expr "]")*
+func (p *parser) parsePostfix() (node, error) {
+	x, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.accept("."):
+			tok := p.next()
+			if tok.kind != tokIdent {
+				return nil, &Error{Pos: tok.pos, Msg: "expected field name"}
+			}
+			x = &member{obj: x, key: &literal{val: tok.text}}
+		case p.accept("["):
+			key, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expect("]"); err != nil {
+				return nil, err
+			}
+			x = &member{obj: x, key: key}
+		default:
+			return x, nil
+		}
+	}
+}
+
+// parsePrimary primary := number | string | true | false | null | ident | ident "(" args ")" | "(" expr ")" | "[" items "]"
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		return &literal{val: tok.num}, nil
+	case tokString:
+		return &literal{val: tok.text}, nil
+	case tokIdent:
+		switch tok.text {
+		case "true":
+			return &literal{val: true}, nil
+		case "false":
+			return &literal{val: false}, nil
+		case "null":
+			return &literal{val: nil}, nil
+		case "in":
+			return nil, &Error{Pos: tok.pos, Msg: `unexpected "in"`}
+		}
+		if p.accept("(") {
+			return p.parseCall(tok)
+		}
+		p.vars[tok.text] = true
+		return &ident{name: tok.text}, nil
+	case tokOp:
+		switch tok.text {
+		case "(":
+			x, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expect(")"); err != nil {
+				return nil, err
+			}
+			return x, nil
+		case "[":
+			items, err := p.parseList("]")
+			if err != nil {
+				return nil, err
+			}
+			return &list{items: items}, nil
+		}
+	case tokEOF:
+		return nil, &Error{Pos: tok.pos, Msg: "unexpected end of expression"}
+	}
+	return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("unexpected %q", tok.text)}
+}
+
+// parseCall 解析函数调用，函数名和参数个数在编译时校验
+func (p *parser) parseCall(name token) (node, error) {
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, &Error{Pos: name.pos, Msg: fmt.Sprintf("unknown function %q", name.text)}
+	}
+	args, err := p.parseList(")")
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != fn.arity {
+		return nil, &Error{Pos: name.pos, Msg: fmt.Sprintf("%s expects %d arguments", name.text, fn.arity)}
+	}
+	return &call{name: name.text, fn: fn.call, args: args}, nil
+}
+
+// parseList 解析以逗号分隔、以 end 结尾的表达式列表
+func (p *parser) parseList(end string) ([]node, error) {
+	items := make([]node, 0)
+	if p.accept(end) {
+		return items, nil
+	}
+	for {
+		item, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if p.accept(end) {
+			return items, nil
+		}
+		if err = p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
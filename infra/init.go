@@ -1,19 +1,28 @@
 package infra
 
 import (
+	"context"
 	"fmt"
 	"go-tpl/infra/config"
 	"go-tpl/infra/dbs"
 	"go-tpl/infra/logger"
+	"go-tpl/infra/mail"
+	"go-tpl/infra/oidc"
+	"go-tpl/infra/password"
 
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 )
 
 var (
-	Cfg *config.Config
-	DB  *gorm.DB
-	RDB *redis.Client
+	Cfg    *config.Config
+	DB     *gorm.DB
+	RDB    *redis.Client
+	Mailer mail.Mailer
+	OIDC   oidc.Providers
+
+	PasswordPolicy *password.Policy
+	PasswordHasher *password.Hasher
 )
 
 func Init() {
@@ -38,6 +47,30 @@ func Init() {
 		panic(fmt.Sprintf("Failed to connect to redis: %v", err))
 	}
 
+	// 4.初始化邮件发送
+	Mailer, err = mail.New(Cfg.Mail)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to init mailer: %v", err))
+	}
+	if Cfg.Mail.Driver == "log" {
+		logger.Warn(context.Background(), "mail driver is log, reset and verification links are written to logs, do not use in production")
+	}
+
+	// 5.初始化 OIDC 提供方
+	OIDC, err = oidc.New(Cfg.OIDC)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to init oidc providers: %v", err))
+	}
+
+	// 6.加载密码策略
+	PasswordPolicy, err = password.NewPolicy(Cfg.Password)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to load password policy: %v", err))
+	}
+	PasswordHasher, err = password.NewHasher(Cfg.Password)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to init password hasher: %v", err))
+	}
 }
 
 func ProvideDB() *gorm.DB {
@@ -47,3 +80,19 @@ func ProvideDB() *gorm.DB {
 func ProvideRDB() *redis.Client {
 	return RDB
 }
+
+func ProvideMailer() mail.Mailer {
+	return Mailer
+}
+
+func ProvideOIDC() oidc.Providers {
+	return OIDC
+}
+
+func ProvidePasswordPolicy() *password.Policy {
+	return PasswordPolicy
+}
+
+func ProvidePasswordHasher() *password.Hasher {
+	return PasswordHasher
+}
@@ -1,86 +1,233 @@
 package jwt
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"go-tpl/infra"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
 )
 
 type TokenType string
 
 const (
-	AccessTokenType  TokenType = "access"
-	RefreshTokenType TokenType = "refresh"
+	AccessTokenType    TokenType = "access"
+	RefreshTokenType   TokenType = "refresh"
+	ChallengeTokenType TokenType = "challenge"    // 两步验证登录挑战
+	EmailTokenType     TokenType = "email_verify" // 邮箱验证
 )
 
+const (
+	challengeExpireTime     = 300   // 两步验证挑战 token 有效期（秒）
+	emailExpireTime         = 86400 // 邮箱验证 token 有效期（秒）
+	impersonationExpireTime = 900   // 模拟登录 token 有效期（秒）
+)
+
+const (
+	// refreshFamilyKey 记录 token 家族当前唯一有效的 refresh token jti
+	refreshFamilyKey = "jwt:refresh_family:%s"
+)
+
+var (
+	ErrRefreshTokenReused  = errors.New("refresh token reused")
+	ErrRefreshTokenRevoked = errors.New("refresh token revoked or expired")
+)
+
+// ParseToken 返回的校验错误，均可用 errors.Is(err, ErrTokenInvalid) 判断
+var (
+	ErrTokenInvalid     = errors.New("invalid token")
+	ErrTokenExpired     = fmt.Errorf("%w: expired", ErrTokenInvalid)
+	ErrTokenNotValidYet = fmt.Errorf("%w: not valid yet", ErrTokenInvalid)
+	ErrTokenIssuer      = fmt.Errorf("%w: wrong issuer", ErrTokenInvalid)
+	ErrTokenAudience    = fmt.Errorf("%w: wrong audience", ErrTokenInvalid)
+	ErrTokenSignature   = fmt.Errorf("%w: bad signature", ErrTokenInvalid)
+)
+
+// rotateScript 原子地校验并轮换 refresh token
+// 返回 1: 轮换成功; 0: 检测到重放, 已吊销整个家族; -1: 家族不存在(已吊销或过期)
+var rotateScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if not current then
+	return -1
+end
+if current ~= ARGV[1] then
+	redis.call('DEL', KEYS[1])
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[2], 'EX', ARGV[3])
+return 1
+`)
+
 type Claims struct {
-	UserID uint      `json:"user_id"`
-	Type   TokenType `json:"type"`
+	UserID   uint      `json:"user_id"`
+	Type     TokenType `json:"type"`
+	FamilyID string    `json:"fid,omitempty"`   // refresh token 家族 ID
+	Email    string    `json:"email,omitempty"` // 待验证的邮箱
+	Actor    *Actor    `json:"act,omitempty"`   // 模拟登录时的实际操作人 (RFC 8693)
+	IssuedMs int64     `json:"iat_ms"`          // 毫秒精度的签发时间，iat 仅精确到秒
 	jwt.RegisteredClaims
 }
 
+// Actor 模拟登录的实际操作人
+type Actor struct {
+	UserID uint `json:"user_id"`
+}
+
+// Impersonated 是否为模拟其他用户的 token
+func (c *Claims) Impersonated() bool {
+	return c.Actor != nil
+}
+
+// ImpersonationToken 模拟登录 token，仅包含短期 access token，不可刷新
+type ImpersonationToken struct {
+	AccessToken string    `json:"access_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	TokenID     string    `json:"-"`
+}
+
 type TokenPair struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
+	FamilyID     string `json:"-"` // 所属 refresh token 家族，对应一个登录会话
 }
 
-// generateToken 生成指定类型的 JWT token
-func generateToken(userID uint, tokenType TokenType, expireTime int64) (string, error) {
-	// 获取配置
-	secret := infra.Cfg.JWT.Secret
+// newTokenID 生成随机 token ID，用作 jti 和家族 ID
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
 
-	if secret == "" {
-		return "", errors.New("JWT secret not configured")
+// generateToken 生成指定类型的 JWT token
+func generateToken(claims Claims, tokenID string, expireTime int64) (string, error) {
+	// 获取签名密钥
+	ks, err := loadKeySet()
+	if err != nil {
+		return "", err
 	}
 
-	// 创建 Claims
-	claims := Claims{
-		UserID: userID,
-		Type:   tokenType,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(expireTime) * time.Second)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-		},
+	// 补充标准 Claims
+	now := time.Now()
+	claims.IssuedMs = now.UnixMilli()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        tokenID,
+		Issuer:    infra.Cfg.JWT.Issuer,
+		Audience:  infra.Cfg.JWT.Audience,
+		ExpiresAt: jwt.NewNumericDate(now.Add(time.Duration(expireTime) * time.Second)),
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
 	}
 
 	// 生成 token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return ks.sign(claims)
+}
+
+// accessExpireTime 获取 access_token 过期时间
+func accessExpireTime() int64 {
+	if infra.Cfg.JWT.ExpireTime == 0 {
+		return 7200 // 默认2小时
+	}
+	return infra.Cfg.JWT.ExpireTime
+}
+
+// refreshExpireTime 获取 refresh_token 过期时间
+func refreshExpireTime() int64 {
+	if infra.Cfg.JWT.RefreshExpireTime == 0 {
+		return 604800 // 默认7天
+	}
+	return infra.Cfg.JWT.RefreshExpireTime
+}
+
+// leeway 获取校验 exp/nbf 时允许的时钟偏差
+func leeway() time.Duration {
+	return time.Duration(infra.Cfg.JWT.Leeway) * time.Second
 }
 
 // GenerateToken 生成 JWT token (保持向后兼容)
 func GenerateToken(userID uint) (string, error) {
-	expireTime := infra.Cfg.JWT.ExpireTime
-	if expireTime == 0 {
-		expireTime = 7200 // 默认2小时
+	tokenID, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+	return generateToken(Claims{UserID: userID, Type: AccessTokenType}, tokenID, accessExpireTime())
+}
+
+// GenerateChallengeToken 生成两步验证挑战 token，仅可用于换取正式 token 对
+func GenerateChallengeToken(userID uint) (string, error) {
+	tokenID, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+	return generateToken(Claims{UserID: userID, Type: ChallengeTokenType}, tokenID, challengeExpireTime)
+}
+
+// GenerateEmailToken 生成邮箱验证 token，绑定用户 ID 和邮箱
+func GenerateEmailToken(userID uint, email string) (string, error) {
+	tokenID, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+	return generateToken(Claims{UserID: userID, Type: EmailTokenType, Email: email}, tokenID, emailExpireTime)
+}
+
+// GenerateImpersonationToken 生成以 actorID 身份模拟 userID 的短期 access token
+func GenerateImpersonationToken(userID, actorID uint) (*ImpersonationToken, error) {
+	tokenID, err := newTokenID()
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(impersonationExpireTime * time.Second)
+	token, err := generateToken(Claims{UserID: userID, Type: AccessTokenType, Actor: &Actor{UserID: actorID}}, tokenID, impersonationExpireTime)
+	if err != nil {
+		return nil, err
 	}
-	return generateToken(userID, AccessTokenType, expireTime)
+	return &ImpersonationToken{AccessToken: token, ExpiresAt: expiresAt, TokenID: tokenID}, nil
 }
 
-// GenerateTokenPair 生成 access_token 和 refresh_token
-func GenerateTokenPair(userID uint) (*TokenPair, error) {
-	// 获取配置
-	accessExpireTime := infra.Cfg.JWT.ExpireTime
-	if accessExpireTime == 0 {
-		accessExpireTime = 7200 // 默认2小时
+// GenerateTokenPair 生成 access_token 和 refresh_token，并开启新的 refresh token 家族
+func GenerateTokenPair(ctx context.Context, userID uint) (*TokenPair, error) {
+	familyID, err := newTokenID()
+	if err != nil {
+		return nil, err
+	}
+
+	refreshID, err := newTokenID()
+	if err != nil {
+		return nil, err
 	}
 
-	refreshExpireTime := infra.Cfg.JWT.RefreshExpireTime
-	if refreshExpireTime == 0 {
-		refreshExpireTime = 604800 // 默认7天
+	// 记录家族当前有效的 refresh token
+	key := fmt.Sprintf(refreshFamilyKey, familyID)
+	if err = infra.RDB.Set(ctx, key, refreshID, time.Duration(refreshExpireTime())*time.Second).Err(); err != nil {
+		return nil, err
+	}
+
+	return generateTokenPair(userID, familyID, refreshID)
+}
+
+// generateTokenPair 生成属于指定家族的 token 对
+func generateTokenPair(userID uint, familyID, refreshID string) (*TokenPair, error) {
+	accessID, err := newTokenID()
+	if err != nil {
+		return nil, err
 	}
 
 	// 生成 access token
-	accessToken, err := generateToken(userID, AccessTokenType, accessExpireTime)
+	accessToken, err := generateToken(Claims{UserID: userID, Type: AccessTokenType, FamilyID: familyID}, accessID, accessExpireTime())
 	if err != nil {
 		return nil, err
 	}
 
 	// 生成 refresh token
-	refreshToken, err := generateToken(userID, RefreshTokenType, refreshExpireTime)
+	refreshToken, err := generateToken(Claims{UserID: userID, Type: RefreshTokenType, FamilyID: familyID}, refreshID, refreshExpireTime())
 	if err != nil {
 		return nil, err
 	}
@@ -88,34 +235,64 @@ func GenerateTokenPair(userID uint) (*TokenPair, error) {
 	return &TokenPair{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
+		FamilyID:     familyID,
 	}, nil
 }
 
-// ParseToken 解析 JWT token
+// ParseToken 解析并校验 JWT token，校验失败时返回 ErrTokenExpired 等类型化错误
 func ParseToken(tokenString string) (*Claims, error) {
-	secret := infra.Cfg.JWT.Secret
-	if secret == "" {
-		return nil, errors.New("JWT secret not configured")
+	ks, err := loadKeySet()
+	if err != nil {
+		return nil, err
 	}
 
-	// 解析 token
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
-	})
+	cfg := infra.Cfg.JWT
+	opts := []jwt.ParserOption{jwt.WithLeeway(leeway()), jwt.WithExpirationRequired()}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if len(cfg.Audience) > 0 {
+		opts = append(opts, jwt.WithAudience(cfg.Audience...))
+	}
 
+	// 解析 token，按 kid 选择验证密钥
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, ks.keyFunc, opts...)
 	if err != nil {
-		return nil, err
+		return nil, validationError(err)
 	}
 
 	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
 		return claims, nil
 	}
 
-	return nil, errors.New("invalid token")
+	return nil, ErrTokenInvalid
+}
+
+// validationError 将 jwt 库的校验错误转换为类型化错误，保留原始错误信息
+func validationError(err error) error {
+	var typed error
+	switch {
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid), errors.Is(err, jwt.ErrTokenUnverifiable):
+		typed = ErrTokenSignature
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		typed = ErrTokenInvalid
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		typed = ErrTokenIssuer
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		typed = ErrTokenAudience
+	case errors.Is(err, jwt.ErrTokenExpired):
+		typed = ErrTokenExpired
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		typed = ErrTokenNotValidYet
+	default:
+		typed = ErrTokenInvalid
+	}
+	return fmt.Errorf("%w: %w", typed, err)
 }
 
 // RefreshToken 使用 refresh_token 刷新 token 对
-func RefreshToken(refreshToken string) (*TokenPair, error) {
+// refresh token 仅可使用一次，重复使用已轮换的 refresh token 将吊销整个家族
+func RefreshToken(ctx context.Context, refreshToken string) (*TokenPair, error) {
 	claims, err := ParseToken(refreshToken)
 	if err != nil {
 		return nil, err
@@ -125,7 +302,72 @@ func RefreshToken(refreshToken string) (*TokenPair, error) {
 	if claims.Type != RefreshTokenType {
 		return nil, errors.New("invalid token type: expected refresh token")
 	}
+	if claims.FamilyID == "" || claims.ID == "" {
+		return nil, ErrRefreshTokenRevoked
+	}
+
+	// 检查用户 token 是否已被整体吊销
+	revoked, err := IsRevoked(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrRefreshTokenRevoked
+	}
+
+	newRefreshID, err := newTokenID()
+	if err != nil {
+		return nil, err
+	}
+
+	// 原子轮换家族当前有效的 refresh token
+	key := fmt.Sprintf(refreshFamilyKey, claims.FamilyID)
+	result, err := rotateScript.Run(ctx, infra.RDB, []string{key}, claims.ID, newRefreshID, refreshExpireTime()).Int()
+	if err != nil {
+		return nil, err
+	}
+	switch result {
+	case 0:
+		// 同时使该家族已签发的 access token 失效
+		if err = markFamilyRevoked(ctx, claims.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	case -1:
+		return nil, ErrRefreshTokenRevoked
+	}
 
 	// 生成新的 token 对
-	return GenerateTokenPair(claims.UserID)
+	return generateTokenPair(claims.UserID, claims.FamilyID, newRefreshID)
+}
+
+// RevokeFamily 吊销整个 refresh token 家族，该家族已签发的 access token 同时失效
+func RevokeFamily(ctx context.Context, familyID string) error {
+	if err := infra.RDB.Del(ctx, fmt.Sprintf(refreshFamilyKey, familyID)).Err(); err != nil {
+		return err
+	}
+	return markFamilyRevoked(ctx, familyID)
+}
+
+// ActiveFamilies 返回仍然有效（未吊销且未过期）的 refresh token 家族
+func ActiveFamilies(ctx context.Context, familyIDs []string) (map[string]bool, error) {
+	active := make(map[string]bool, len(familyIDs))
+	if len(familyIDs) == 0 {
+		return active, nil
+	}
+
+	keys := make([]string, len(familyIDs))
+	for i, id := range familyIDs {
+		keys[i] = fmt.Sprintf(refreshFamilyKey, id)
+	}
+	values, err := infra.RDB.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range values {
+		if v != nil {
+			active[familyIDs[i]] = true
+		}
+	}
+	return active, nil
 }
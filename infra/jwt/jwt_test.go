@@ -1,16 +1,29 @@
 package jwt
 
 import (
+	"context"
 	"go-tpl/infra"
 	"go-tpl/infra/config"
 	"testing"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+func setupTestRedis(t *testing.T) *miniredis.Miniredis {
+	mr := miniredis.RunT(t)
+	infra.RDB = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	return mr
+}
+
 func TestJWT(t *testing.T) {
+	ctx := context.Background()
+	setupTestRedis(t)
+
 	// 设置测试配置
 	infra.Cfg = &config.Config{
 		JWT: config.JWTConfig{
@@ -23,7 +36,7 @@ func TestJWT(t *testing.T) {
 	t.Run("GenerateTokenPair", func(t *testing.T) {
 		userID := uint(123)
 
-		tokenPair, err := GenerateTokenPair(userID)
+		tokenPair, err := GenerateTokenPair(ctx, userID)
 		require.NoError(t, err)
 		assert.NotEmpty(t, tokenPair.AccessToken)
 		assert.NotEmpty(t, tokenPair.RefreshToken)
@@ -33,7 +46,7 @@ func TestJWT(t *testing.T) {
 	t.Run("ParseAccessToken", func(t *testing.T) {
 		userID := uint(456)
 
-		tokenPair, err := GenerateTokenPair(userID)
+		tokenPair, err := GenerateTokenPair(ctx, userID)
 		require.NoError(t, err)
 
 		// 解析 access token
@@ -46,7 +59,7 @@ func TestJWT(t *testing.T) {
 	t.Run("ParseRefreshToken", func(t *testing.T) {
 		userID := uint(789)
 
-		tokenPair, err := GenerateTokenPair(userID)
+		tokenPair, err := GenerateTokenPair(ctx, userID)
 		require.NoError(t, err)
 
 		// 解析 refresh token
@@ -60,11 +73,11 @@ func TestJWT(t *testing.T) {
 		userID := uint(999)
 
 		// 生成初始 token 对
-		tokenPair, err := GenerateTokenPair(userID)
+		tokenPair, err := GenerateTokenPair(ctx, userID)
 		require.NoError(t, err)
 
 		// 使用 refresh token 生成新的 token 对
-		newTokenPair, err := RefreshToken(tokenPair.RefreshToken)
+		newTokenPair, err := RefreshToken(ctx, tokenPair.RefreshToken)
 		require.NoError(t, err)
 		assert.NotEmpty(t, newTokenPair.AccessToken)
 		assert.NotEmpty(t, newTokenPair.RefreshToken)
@@ -91,11 +104,11 @@ func TestJWT(t *testing.T) {
 	t.Run("RefreshTokenWithAccessToken", func(t *testing.T) {
 		userID := uint(111)
 
-		tokenPair, err := GenerateTokenPair(userID)
+		tokenPair, err := GenerateTokenPair(ctx, userID)
 		require.NoError(t, err)
 
 		// 尝试使用 access token 刷新（应该失败）
-		_, err = RefreshToken(tokenPair.AccessToken)
+		_, err = RefreshToken(ctx, tokenPair.AccessToken)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "invalid token type: expected refresh token")
 	})
@@ -107,7 +120,7 @@ func TestJWT(t *testing.T) {
 
 		userID := uint(222)
 
-		tokenPair, err := GenerateTokenPair(userID)
+		tokenPair, err := GenerateTokenPair(ctx, userID)
 		require.NoError(t, err)
 
 		// 等待 token 过期
@@ -123,6 +136,9 @@ func TestJWT(t *testing.T) {
 }
 
 func TestGenerateToken(t *testing.T) {
+	ctx := context.Background()
+	setupTestRedis(t)
+
 	// 设置测试配置
 	infra.Cfg = &config.Config{
 		JWT: config.JWTConfig{
@@ -154,7 +170,7 @@ func TestGenerateToken(t *testing.T) {
 
 		userID := uint(444)
 
-		tokenPair, err := GenerateTokenPair(userID)
+		tokenPair, err := GenerateTokenPair(ctx, userID)
 		require.NoError(t, err)
 
 		// 验证 token 可以解析
@@ -166,4 +182,304 @@ func TestGenerateToken(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, userID, refreshClaims.UserID)
 	})
+
+	t.Run("EmailToken", func(t *testing.T) {
+		token, err := GenerateEmailToken(555, "alice@example.com")
+		require.NoError(t, err)
+
+		claims, err := ParseToken(token)
+		require.NoError(t, err)
+		assert.Equal(t, uint(555), claims.UserID)
+		assert.Equal(t, EmailTokenType, claims.Type)
+		assert.Equal(t, "alice@example.com", claims.Email)
+	})
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	ctx := context.Background()
+	mr := setupTestRedis(t)
+
+	infra.Cfg = &config.Config{
+		JWT: config.JWTConfig{
+			Secret:            "test-secret-key",
+			ExpireTime:        3600,
+			RefreshExpireTime: 7 * 86400,
+		},
+	}
+
+	t.Run("Rotation", func(t *testing.T) {
+		tokenPair, err := GenerateTokenPair(ctx, 1)
+		require.NoError(t, err)
+
+		newTokenPair, err := RefreshToken(ctx, tokenPair.RefreshToken)
+		require.NoError(t, err)
+
+		// 新旧 refresh token 属于同一家族，但 jti 不同
+		oldClaims, err := ParseToken(tokenPair.RefreshToken)
+		require.NoError(t, err)
+		newClaims, err := ParseToken(newTokenPair.RefreshToken)
+		require.NoError(t, err)
+		assert.Equal(t, oldClaims.FamilyID, newClaims.FamilyID)
+		assert.NotEqual(t, oldClaims.ID, newClaims.ID)
+
+		// 新 refresh token 可继续轮换
+		_, err = RefreshToken(ctx, newTokenPair.RefreshToken)
+		require.NoError(t, err)
+	})
+
+	t.Run("ReuseDetection", func(t *testing.T) {
+		tokenPair, err := GenerateTokenPair(ctx, 2)
+		require.NoError(t, err)
+
+		newTokenPair, err := RefreshToken(ctx, tokenPair.RefreshToken)
+		require.NoError(t, err)
+
+		// 重复使用已轮换的 refresh token
+		_, err = RefreshToken(ctx, tokenPair.RefreshToken)
+		assert.ErrorIs(t, err, ErrRefreshTokenReused)
+
+		// 整个家族被吊销，最新的 refresh token 也不可再用
+		_, err = RefreshToken(ctx, newTokenPair.RefreshToken)
+		assert.ErrorIs(t, err, ErrRefreshTokenRevoked)
+	})
+
+	t.Run("Expiry", func(t *testing.T) {
+		tokenPair, err := GenerateTokenPair(ctx, 3)
+		require.NoError(t, err)
+
+		// 家族记录随 refresh token 有效期过期
+		mr.FastForward(7*86400*time.Second + time.Second)
+
+		_, err = RefreshToken(ctx, tokenPair.RefreshToken)
+		assert.ErrorIs(t, err, ErrRefreshTokenRevoked)
+	})
+
+	t.Run("RevokeFamily", func(t *testing.T) {
+		tokenPair, err := GenerateTokenPair(ctx, 4)
+		require.NoError(t, err)
+
+		claims, err := ParseToken(tokenPair.RefreshToken)
+		require.NoError(t, err)
+		require.NoError(t, RevokeFamily(ctx, claims.FamilyID))
+
+		_, err = RefreshToken(ctx, tokenPair.RefreshToken)
+		assert.ErrorIs(t, err, ErrRefreshTokenRevoked)
+
+		// 该家族的 access token 立即失效
+		accessClaims, err := ParseToken(tokenPair.AccessToken)
+		require.NoError(t, err)
+		revoked, err := IsRevoked(ctx, accessClaims)
+		require.NoError(t, err)
+		assert.True(t, revoked)
+	})
+
+	t.Run("ActiveFamilies", func(t *testing.T) {
+		active, err := GenerateTokenPair(ctx, 5)
+		require.NoError(t, err)
+		revoked, err := GenerateTokenPair(ctx, 5)
+		require.NoError(t, err)
+		require.NoError(t, RevokeFamily(ctx, revoked.FamilyID))
+
+		families, err := ActiveFamilies(ctx, []string{active.FamilyID, revoked.FamilyID})
+		require.NoError(t, err)
+		assert.True(t, families[active.FamilyID])
+		assert.False(t, families[revoked.FamilyID])
+	})
+}
+
+func TestRevoke(t *testing.T) {
+	ctx := context.Background()
+	setupTestRedis(t)
+
+	infra.Cfg = &config.Config{
+		JWT: config.JWTConfig{
+			Secret:            "test-secret-key",
+			ExpireTime:        3600,
+			RefreshExpireTime: 7 * 86400,
+		},
+	}
+
+	t.Run("RevokeToken", func(t *testing.T) {
+		tokenPair, err := GenerateTokenPair(ctx, 1)
+		require.NoError(t, err)
+
+		claims, err := ParseToken(tokenPair.AccessToken)
+		require.NoError(t, err)
+
+		revoked, err := IsRevoked(ctx, claims)
+		require.NoError(t, err)
+		assert.False(t, revoked)
+
+		require.NoError(t, RevokeToken(ctx, claims))
+
+		revoked, err = IsRevoked(ctx, claims)
+		require.NoError(t, err)
+		assert.True(t, revoked)
+	})
+
+	t.Run("RevokeUserTokens", func(t *testing.T) {
+		tokenPair, err := GenerateTokenPair(ctx, 2)
+		require.NoError(t, err)
+
+		require.NoError(t, RevokeUserTokens(ctx, 2))
+
+		claims, err := ParseToken(tokenPair.AccessToken)
+		require.NoError(t, err)
+		revoked, err := IsRevoked(ctx, claims)
+		require.NoError(t, err)
+		assert.True(t, revoked)
+
+		// 已吊销用户的 refresh token 不可再用
+		_, err = RefreshToken(ctx, tokenPair.RefreshToken)
+		assert.ErrorIs(t, err, ErrRefreshTokenRevoked)
+
+		// 其他用户不受影响
+		otherPair, err := GenerateTokenPair(ctx, 3)
+		require.NoError(t, err)
+		otherClaims, err := ParseToken(otherPair.AccessToken)
+		require.NoError(t, err)
+		revoked, err = IsRevoked(ctx, otherClaims)
+		require.NoError(t, err)
+		assert.False(t, revoked)
+
+		// 吊销后签发的 token 不受影响，即使与吊销时间点在同一秒内
+		time.Sleep(2 * time.Millisecond)
+		newPair, err := GenerateTokenPair(ctx, 2)
+		require.NoError(t, err)
+		newClaims, err := ParseToken(newPair.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, newClaims.IssuedAt.Unix(), newClaims.IssuedMs/1000)
+		revoked, err = IsRevoked(ctx, newClaims)
+		require.NoError(t, err)
+		assert.False(t, revoked)
+	})
+
+	t.Run("ImpersonationToken", func(t *testing.T) {
+		token, err := GenerateImpersonationToken(4, 5)
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(impersonationExpireTime*time.Second), token.ExpiresAt, time.Second)
+
+		claims, err := ParseToken(token.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, uint(4), claims.UserID)
+		assert.Equal(t, AccessTokenType, claims.Type)
+		assert.True(t, claims.Impersonated())
+		assert.Equal(t, uint(5), claims.Actor.UserID)
+		assert.Equal(t, token.TokenID, claims.ID)
+
+		revoked, err := IsRevoked(ctx, claims)
+		require.NoError(t, err)
+		assert.False(t, revoked)
+
+		// 实际操作人的 token 被吊销后，模拟 token 同时失效
+		require.NoError(t, RevokeUserTokens(ctx, 5))
+		revoked, err = IsRevoked(ctx, claims)
+		require.NoError(t, err)
+		assert.True(t, revoked)
+	})
+}
+
+func TestTokenValidation(t *testing.T) {
+	setupTestRedis(t)
+
+	infra.Cfg = &config.Config{
+		JWT: config.JWTConfig{
+			Secret:     "test-secret-key",
+			ExpireTime: 3600,
+			Issuer:     "go-tpl",
+			Audience:   []string{"api", "admin"},
+			Leeway:     30,
+		},
+	}
+
+	// sign 使用当前密钥签发自定义时间的 token
+	sign := func(t *testing.T, iss string, aud []string, exp, nbf time.Time) string {
+		ks, err := loadKeySet()
+		require.NoError(t, err)
+		token, err := ks.sign(Claims{UserID: 1, Type: AccessTokenType, RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    iss,
+			Audience:  aud,
+			ExpiresAt: jwt.NewNumericDate(exp),
+			NotBefore: jwt.NewNumericDate(nbf),
+		}})
+		require.NoError(t, err)
+		return token
+	}
+
+	t.Run("EmitIssuerAndAudience", func(t *testing.T) {
+		token, err := GenerateToken(1)
+		require.NoError(t, err)
+
+		claims, err := ParseToken(token)
+		require.NoError(t, err)
+		assert.Equal(t, "go-tpl", claims.Issuer)
+		assert.Equal(t, jwt.ClaimStrings{"api", "admin"}, claims.Audience)
+	})
+
+	t.Run("AcceptAnyConfiguredAudience", func(t *testing.T) {
+		token := sign(t, "go-tpl", []string{"admin"}, time.Now().Add(time.Hour), time.Now())
+		_, err := ParseToken(token)
+		assert.NoError(t, err)
+	})
+
+	t.Run("WrongAudience", func(t *testing.T) {
+		token := sign(t, "go-tpl", []string{"other-app"}, time.Now().Add(time.Hour), time.Now())
+		_, err := ParseToken(token)
+		assert.ErrorIs(t, err, ErrTokenAudience)
+		assert.ErrorIs(t, err, ErrTokenInvalid)
+
+		// 缺少 aud 的 token 同样拒绝
+		token = sign(t, "go-tpl", nil, time.Now().Add(time.Hour), time.Now())
+		_, err = ParseToken(token)
+		assert.ErrorIs(t, err, ErrTokenInvalid)
+	})
+
+	t.Run("WrongIssuer", func(t *testing.T) {
+		token := sign(t, "other", []string{"api"}, time.Now().Add(time.Hour), time.Now())
+		_, err := ParseToken(token)
+		assert.ErrorIs(t, err, ErrTokenIssuer)
+	})
+
+	t.Run("Leeway", func(t *testing.T) {
+		// 过期时间在容忍范围内
+		token := sign(t, "go-tpl", []string{"api"}, time.Now().Add(-10*time.Second), time.Now().Add(-time.Hour))
+		_, err := ParseToken(token)
+		assert.NoError(t, err)
+
+		token = sign(t, "go-tpl", []string{"api"}, time.Now().Add(time.Hour), time.Now().Add(10*time.Second))
+		_, err = ParseToken(token)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		token := sign(t, "go-tpl", []string{"api"}, time.Now().Add(-time.Minute), time.Now().Add(-time.Hour))
+		_, err := ParseToken(token)
+		assert.ErrorIs(t, err, ErrTokenExpired)
+	})
+
+	t.Run("NotValidYet", func(t *testing.T) {
+		token := sign(t, "go-tpl", []string{"api"}, time.Now().Add(time.Hour), time.Now().Add(time.Minute))
+		_, err := ParseToken(token)
+		assert.ErrorIs(t, err, ErrTokenNotValidYet)
+	})
+
+	t.Run("BadSignature", func(t *testing.T) {
+		token, err := GenerateToken(1)
+		require.NoError(t, err)
+
+		// 更换密钥后原 token 签名无效
+		infra.Cfg = &config.Config{
+			JWT: config.JWTConfig{
+				Secret:   "another-secret-key",
+				Issuer:   "go-tpl",
+				Audience: []string{"api"},
+			},
+		}
+		_, err = ParseToken(token)
+		assert.ErrorIs(t, err, ErrTokenSignature)
+
+		_, err = ParseToken("not-a-token")
+		assert.ErrorIs(t, err, ErrTokenInvalid)
+		assert.NotErrorIs(t, err, ErrTokenSignature)
+	})
 }
@@ -0,0 +1,281 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"go-tpl/infra"
+	"go-tpl/infra/config"
+	"math/big"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signingKey 签名/验证密钥
+type signingKey struct {
+	id         string
+	method     jwt.SigningMethod
+	signKey    any // 签名密钥，退役密钥为 nil
+	verifyKey  any // 验证密钥
+	asymmetric bool
+}
+
+type keySet struct {
+	active *signingKey
+	keys   map[string]*signingKey
+	legacy *signingKey // 未配置 kid 时使用 Secret 的 HS256 密钥
+
+	legacyUntil time.Time // 配置 ActiveKey 后 legacy 密钥的验证截止时间
+}
+
+var keyCache struct {
+	sync.Mutex
+	cfg *config.Config
+	set *keySet
+}
+
+// loadKeySet 加载密钥配置，配置变化时重新加载
+func loadKeySet() (*keySet, error) {
+	keyCache.Lock()
+	defer keyCache.Unlock()
+
+	if keyCache.set != nil && keyCache.cfg == infra.Cfg {
+		return keyCache.set, nil
+	}
+
+	set, err := newKeySet(infra.Cfg.JWT)
+	if err != nil {
+		return nil, err
+	}
+	keyCache.cfg = infra.Cfg
+	keyCache.set = set
+	return set, nil
+}
+
+func newKeySet(cfg config.JWTConfig) (*keySet, error) {
+	set := &keySet{keys: make(map[string]*signingKey)}
+
+	if cfg.Secret != "" {
+		set.legacy = &signingKey{
+			method:    jwt.SigningMethodHS256,
+			signKey:   []byte(cfg.Secret),
+			verifyKey: []byte(cfg.Secret),
+		}
+	}
+
+	for _, kc := range cfg.Keys {
+		key, err := parseKey(kc)
+		if err != nil {
+			return nil, fmt.Errorf("load jwt key %q failed: %w", kc.ID, err)
+		}
+		set.keys[key.id] = key
+	}
+
+	if cfg.ActiveKey == "" {
+		set.active = set.legacy
+	} else {
+		set.active = set.keys[cfg.ActiveKey]
+		if set.active == nil {
+			return nil, fmt.Errorf("jwt active key %q not found", cfg.ActiveKey)
+		}
+		if set.active.signKey == nil {
+			return nil, fmt.Errorf("jwt active key %q has no private key", cfg.ActiveKey)
+		}
+
+		// 切换到 kid 密钥后不再信任共享的 Secret，仅在显式配置的迁移截止时间之前接受
+		if cfg.AcceptLegacySecretUntil == "" {
+			set.legacy = nil
+		} else if set.legacy != nil {
+			until, err := time.Parse(time.RFC3339, cfg.AcceptLegacySecretUntil)
+			if err != nil {
+				return nil, fmt.Errorf("invalid jwt accept_legacy_secret_until: %w", err)
+			}
+			set.legacyUntil = until
+		}
+	}
+
+	if set.active == nil {
+		return nil, errors.New("JWT secret not configured")
+	}
+	return set, nil
+}
+
+func parseKey(kc config.JWTKeyConfig) (*signingKey, error) {
+	if kc.ID == "" {
+		return nil, errors.New("key id is required")
+	}
+
+	key := &signingKey{id: kc.ID}
+	switch kc.Algorithm {
+	case "HS256":
+		if kc.Secret == "" {
+			return nil, errors.New("secret is required")
+		}
+		key.method = jwt.SigningMethodHS256
+		key.signKey = []byte(kc.Secret)
+		key.verifyKey = []byte(kc.Secret)
+		return key, nil
+	case "RS256":
+		key.method = jwt.SigningMethodRS256
+	case "ES256":
+		key.method = jwt.SigningMethodES256
+	case "EdDSA":
+		key.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", kc.Algorithm)
+	}
+	key.asymmetric = true
+
+	if kc.PrivateKey != "" {
+		pem, err := os.ReadFile(kc.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		var signer crypto.Signer
+		switch kc.Algorithm {
+		case "RS256":
+			signer, err = jwt.ParseRSAPrivateKeyFromPEM(pem)
+		case "ES256":
+			signer, err = jwt.ParseECPrivateKeyFromPEM(pem)
+		case "EdDSA":
+			var pk crypto.PrivateKey
+			pk, err = jwt.ParseEdPrivateKeyFromPEM(pem)
+			if err == nil {
+				signer, _ = pk.(crypto.Signer)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		key.signKey = signer
+		key.verifyKey = signer.Public()
+	}
+
+	if kc.PublicKey != "" {
+		pem, err := os.ReadFile(kc.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		switch kc.Algorithm {
+		case "RS256":
+			key.verifyKey, err = jwt.ParseRSAPublicKeyFromPEM(pem)
+		case "ES256":
+			key.verifyKey, err = jwt.ParseECPublicKeyFromPEM(pem)
+		case "EdDSA":
+			key.verifyKey, err = jwt.ParseEdPublicKeyFromPEM(pem)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if key.verifyKey == nil {
+		return nil, errors.New("private_key or public_key is required")
+	}
+	return key, nil
+}
+
+// sign 使用当前签名密钥签发 token
+func (ks *keySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.active.method, claims)
+	if ks.active.id != "" {
+		token.Header["kid"] = ks.active.id
+	}
+	return token.SignedString(ks.active.signKey)
+}
+
+// keyFunc 根据 token 头部的 kid 选择验证密钥，并校验算法一致
+func (ks *keySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	key := ks.legacy
+	if kid, ok := token.Header["kid"].(string); ok {
+		key = ks.keys[kid]
+	} else if !ks.legacyUntil.IsZero() && time.Now().After(ks.legacyUntil) {
+		return nil, errors.New("legacy signing key no longer accepted")
+	}
+	if key == nil {
+		return nil, errors.New("unknown signing key")
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %s", token.Method.Alg())
+	}
+	return key.verifyKey, nil
+}
+
+// JWK JSON Web Key
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicJWKS 导出所有非对称密钥（含退役密钥）的公钥
+func PublicJWKS() (*JWKS, error) {
+	ks, err := loadKeySet()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(ks.keys))
+	for id := range ks.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	jwks := &JWKS{Keys: []JWK{}}
+	for _, id := range ids {
+		key := ks.keys[id]
+		if !key.asymmetric {
+			continue
+		}
+		jwk := JWK{Kid: key.id, Use: "sig", Alg: key.method.Alg()}
+		switch pub := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64URL(pub.N.Bytes())
+			jwk.E = base64URL(big.NewInt(int64(pub.E)).Bytes())
+		case *ecdsa.PublicKey:
+			ecdh, err := pub.ECDH()
+			if err != nil {
+				return nil, err
+			}
+			// 未压缩点格式: 0x04 || X || Y
+			point := ecdh.Bytes()[1:]
+			size := len(point) / 2
+			jwk.Kty = "EC"
+			jwk.Crv = pub.Curve.Params().Name
+			jwk.X = base64URL(point[:size])
+			jwk.Y = base64URL(point[size:])
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64URL(pub)
+		default:
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks, nil
+}
+
+func base64URL(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,211 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"go-tpl/infra"
+	"go-tpl/infra/config"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeKeyPair 生成密钥对并写入 PEM 文件，返回私钥和公钥路径
+func writeKeyPair(t *testing.T, algorithm string) (string, string) {
+	var (
+		priv any
+		pub  any
+		err  error
+	)
+	switch algorithm {
+	case "RS256":
+		var k *rsa.PrivateKey
+		k, err = rsa.GenerateKey(rand.Reader, 2048)
+		priv, pub = k, &k.PublicKey
+	case "ES256":
+		var k *ecdsa.PrivateKey
+		k, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		priv, pub = k, &k.PublicKey
+	case "EdDSA":
+		pub, priv, err = ed25519.GenerateKey(rand.Reader)
+	}
+	require.NoError(t, err)
+
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	privPath := filepath.Join(dir, "key.pem")
+	pubPath := filepath.Join(dir, "key.pub.pem")
+	require.NoError(t, os.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}), 0600))
+	require.NoError(t, os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0644))
+	return privPath, pubPath
+}
+
+func TestAsymmetricKeys(t *testing.T) {
+	ctx := context.Background()
+	setupTestRedis(t)
+
+	for _, algorithm := range []string{"RS256", "ES256", "EdDSA"} {
+		t.Run(algorithm, func(t *testing.T) {
+			privPath, _ := writeKeyPair(t, algorithm)
+			infra.Cfg = &config.Config{
+				JWT: config.JWTConfig{
+					ActiveKey: "k1",
+					Keys: []config.JWTKeyConfig{
+						{ID: "k1", Algorithm: algorithm, PrivateKey: privPath},
+					},
+				},
+			}
+
+			tokenPair, err := GenerateTokenPair(ctx, 1)
+			require.NoError(t, err)
+
+			token, _, err := jwt.NewParser().ParseUnverified(tokenPair.AccessToken, &Claims{})
+			require.NoError(t, err)
+			assert.Equal(t, algorithm, token.Method.Alg())
+			assert.Equal(t, "k1", token.Header["kid"])
+
+			claims, err := ParseToken(tokenPair.AccessToken)
+			require.NoError(t, err)
+			assert.Equal(t, uint(1), claims.UserID)
+
+			jwks, err := PublicJWKS()
+			require.NoError(t, err)
+			require.Len(t, jwks.Keys, 1)
+			assert.Equal(t, "k1", jwks.Keys[0].Kid)
+			assert.Equal(t, algorithm, jwks.Keys[0].Alg)
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	setupTestRedis(t)
+
+	oldPriv, oldPub := writeKeyPair(t, "RS256")
+	newPriv, _ := writeKeyPair(t, "ES256")
+
+	// 使用旧密钥签发
+	infra.Cfg = &config.Config{
+		JWT: config.JWTConfig{
+			ActiveKey: "old",
+			Keys: []config.JWTKeyConfig{
+				{ID: "old", Algorithm: "RS256", PrivateKey: oldPriv},
+			},
+		},
+	}
+	oldToken, err := GenerateToken(1)
+	require.NoError(t, err)
+
+	// 轮换：新密钥签名，旧密钥仅保留公钥用于验证
+	infra.Cfg = &config.Config{
+		JWT: config.JWTConfig{
+			ActiveKey: "new",
+			Keys: []config.JWTKeyConfig{
+				{ID: "new", Algorithm: "ES256", PrivateKey: newPriv},
+				{ID: "old", Algorithm: "RS256", PublicKey: oldPub},
+			},
+		},
+	}
+
+	t.Run("RetiredKeyStillVerifies", func(t *testing.T) {
+		claims, err := ParseToken(oldToken)
+		require.NoError(t, err)
+		assert.Equal(t, uint(1), claims.UserID)
+	})
+
+	t.Run("SignWithActiveKey", func(t *testing.T) {
+		newToken, err := GenerateToken(2)
+		require.NoError(t, err)
+
+		token, _, err := jwt.NewParser().ParseUnverified(newToken, &Claims{})
+		require.NoError(t, err)
+		assert.Equal(t, "new", token.Header["kid"])
+
+		_, err = ParseToken(newToken)
+		require.NoError(t, err)
+	})
+
+	t.Run("JWKSIncludesRetiredKeys", func(t *testing.T) {
+		jwks, err := PublicJWKS()
+		require.NoError(t, err)
+		require.Len(t, jwks.Keys, 2)
+		assert.Equal(t, "new", jwks.Keys[0].Kid)
+		assert.Equal(t, "EC", jwks.Keys[0].Kty)
+		assert.Equal(t, "old", jwks.Keys[1].Kid)
+		assert.Equal(t, "RSA", jwks.Keys[1].Kty)
+	})
+
+	t.Run("RejectUnknownKid", func(t *testing.T) {
+		infra.Cfg = &config.Config{
+			JWT: config.JWTConfig{
+				ActiveKey: "new",
+				Keys: []config.JWTKeyConfig{
+					{ID: "new", Algorithm: "ES256", PrivateKey: newPriv},
+				},
+			},
+		}
+		_, err := ParseToken(oldToken)
+		assert.Error(t, err)
+	})
+
+	t.Run("LegacySecret", func(t *testing.T) {
+		infra.Cfg = &config.Config{JWT: config.JWTConfig{Secret: "old-shared-secret"}}
+		legacyToken, err := GenerateToken(3)
+		require.NoError(t, err)
+
+		// 切换到 kid 密钥后，共享 Secret 签发的 token 默认不再被接受
+		rotated := config.JWTConfig{
+			Secret:    "old-shared-secret",
+			ActiveKey: "new",
+			Keys: []config.JWTKeyConfig{
+				{ID: "new", Algorithm: "ES256", PrivateKey: newPriv},
+			},
+		}
+		infra.Cfg = &config.Config{JWT: rotated}
+		_, err = ParseToken(legacyToken)
+		assert.Error(t, err)
+
+		// 迁移截止时间之前仍接受
+		rotated.AcceptLegacySecretUntil = time.Now().Add(time.Hour).Format(time.RFC3339)
+		infra.Cfg = &config.Config{JWT: rotated}
+		claims, err := ParseToken(legacyToken)
+		require.NoError(t, err)
+		assert.Equal(t, uint(3), claims.UserID)
+
+		// 超过截止时间后拒绝
+		rotated.AcceptLegacySecretUntil = time.Now().Add(-time.Minute).Format(time.RFC3339)
+		infra.Cfg = &config.Config{JWT: rotated}
+		_, err = ParseToken(legacyToken)
+		assert.Error(t, err)
+
+		rotated.AcceptLegacySecretUntil = "next week"
+		infra.Cfg = &config.Config{JWT: rotated}
+		_, err = ParseToken(legacyToken)
+		assert.Error(t, err)
+	})
+
+	t.Run("RejectAlgorithmMismatch", func(t *testing.T) {
+		// 使用 HS256 伪造携带 ES256 密钥 kid 的 token
+		forged := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{UserID: 1, Type: AccessTokenType})
+		forged.Header["kid"] = "new"
+		tokenString, err := forged.SignedString([]byte("any-secret"))
+		require.NoError(t, err)
+
+		_, err = ParseToken(tokenString)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,99 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-tpl/infra"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// denylistKey 已吊销的单个 token（按 jti）
+	denylistKey = "jwt:denylist:%s"
+	// userRevokedKey 用户 token 吊销时间点（毫秒），此前签发的 token 全部失效
+	userRevokedKey = "jwt:user_revoked:%d"
+	// familyRevokedKey 已吊销的 token 家族，用于使其 access token 立即失效
+	familyRevokedKey = "jwt:family_revoked:%s"
+)
+
+// RevokeToken 将单个 token 加入黑名单，直到其自然过期
+func RevokeToken(ctx context.Context, claims *Claims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+
+	// 保留到超过时钟偏差容忍范围
+	ttl := time.Until(claims.ExpiresAt.Time) + leeway()
+	if ttl <= 0 {
+		return nil
+	}
+	return infra.RDB.Set(ctx, fmt.Sprintf(denylistKey, claims.ID), 1, ttl).Err()
+}
+
+// RevokeUserTokens 吊销用户在此之前签发的所有 token
+func RevokeUserTokens(ctx context.Context, userID uint) error {
+	// 记录保留到最长的 token 有效期结束即可
+	ttl := time.Duration(max(accessExpireTime(), refreshExpireTime()))*time.Second + leeway()
+	return infra.RDB.Set(ctx, fmt.Sprintf(userRevokedKey, userID), time.Now().UnixMilli(), ttl).Err()
+}
+
+// markFamilyRevoked 标记家族已吊销，保留到其 access token 全部过期
+func markFamilyRevoked(ctx context.Context, familyID string) error {
+	ttl := time.Duration(accessExpireTime())*time.Second + leeway()
+	return infra.RDB.Set(ctx, fmt.Sprintf(familyRevokedKey, familyID), 1, ttl).Err()
+}
+
+// IsRevoked 检查 token 是否已被吊销
+func IsRevoked(ctx context.Context, claims *Claims) (bool, error) {
+	var keys []string
+	if claims.ID != "" {
+		keys = append(keys, fmt.Sprintf(denylistKey, claims.ID))
+	}
+	if claims.FamilyID != "" {
+		keys = append(keys, fmt.Sprintf(familyRevokedKey, claims.FamilyID))
+	}
+	if len(keys) > 0 {
+		n, err := infra.RDB.Exists(ctx, keys...).Result()
+		if err != nil {
+			return false, err
+		}
+		if n > 0 {
+			return true, nil
+		}
+	}
+
+	// 模拟登录的 token 在实际操作人的 token 被吊销时同样失效
+	userIDs := []uint{claims.UserID}
+	if claims.Actor != nil {
+		userIDs = append(userIDs, claims.Actor.UserID)
+	}
+	for _, userID := range userIDs {
+		revoked, err := userRevoked(ctx, userID, claims)
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+	return false, nil
+}
+
+// userRevoked 检查 token 是否签发于用户 token 吊销时间点之前
+func userRevoked(ctx context.Context, userID uint, claims *Claims) (bool, error) {
+	revokedAt, err := infra.RDB.Get(ctx, fmt.Sprintf(userRevokedKey, userID)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	ts, err := strconv.ParseInt(revokedAt, 10, 64)
+	if err != nil {
+		return false, err
+	}
+	// 按毫秒比较，吊销后立即签发的 token（如修改密码后重新登录）不受影响；同一毫秒内签发的 token 视为已吊销
+	return claims.IssuedMs <= ts, nil
+}
@@ -0,0 +1,155 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"go-tpl/infra/config"
+	"go-tpl/infra/logger"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message 邮件内容
+type Message struct {
+	To      []string
+	Subject string
+	Body    string // 纯文本
+}
+
+// Mailer 邮件发送接口
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New 根据配置创建 Mailer，默认使用 SMTP，未配置时返回错误；
+// log 驱动会记录邮件正文（含一次性凭证），须显式配置
+func New(cfg config.MailConfig) (Mailer, error) {
+	switch cfg.Driver {
+	case "", "smtp":
+		if cfg.Host == "" || cfg.From == "" {
+			return nil, fmt.Errorf("smtp mailer requires host and from")
+		}
+		return &SMTPMailer{cfg: cfg}, nil
+	case "log":
+		return &LogMailer{File: cfg.File}, nil
+	default:
+		return nil, fmt.Errorf("unsupported mail driver %q", cfg.Driver)
+	}
+}
+
+// ==================== SMTP ====================
+
+// SMTPMailer 通过 SMTP 发送邮件，465 端口使用隐式 TLS，其余端口在支持时使用 STARTTLS
+type SMTPMailer struct {
+	cfg config.MailConfig
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var (
+		conn net.Conn
+		err  error
+	)
+	if m.cfg.Port == 465 {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: m.cfg.Host})
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && m.cfg.Port != 465 {
+		if err = client.StartTLS(&tls.Config{ServerName: m.cfg.Host}); err != nil {
+			return err
+		}
+	}
+	if m.cfg.Username != "" {
+		if err = client.Auth(smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err = client.Mail(m.cfg.From); err != nil {
+		return err
+	}
+	for _, to := range msg.To {
+		if err = client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(buildMessage(m.cfg.From, msg)); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// buildMessage 构造 RFC 5322 格式的邮件
+func buildMessage(from string, msg Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + strings.Join(msg.To, ", ") + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// ==================== Log ====================
+
+// LogMailer 不实际发送邮件，写入文件或日志，用于本地开发和测试
+type LogMailer struct {
+	File string
+
+	mu sync.Mutex
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	if m.File == "" {
+		logger.Info(ctx, "mail sent",
+			logger.Str("to", strings.Join(msg.To, ",")),
+			logger.Str("subject", msg.Subject),
+			logger.Str("body", msg.Body))
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "To: %s\nSubject: %s\n\n%s\n\n", strings.Join(msg.To, ", "), msg.Subject, msg.Body)
+	return err
+}
@@ -0,0 +1,64 @@
+package mail
+
+import (
+	"context"
+	"go-tpl/infra/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	m, err := New(config.MailConfig{Driver: "log"})
+	require.NoError(t, err)
+	assert.IsType(t, &LogMailer{}, m)
+
+	m, err = New(config.MailConfig{Driver: "smtp", Host: "smtp.example.com", Port: 587, From: "noreply@example.com"})
+	require.NoError(t, err)
+	assert.IsType(t, &SMTPMailer{}, m)
+
+	// 默认使用 SMTP，未配置时不能静默退化为 log 驱动
+	_, err = New(config.MailConfig{})
+	assert.Error(t, err)
+
+	_, err = New(config.MailConfig{Driver: "smtp"})
+	assert.Error(t, err)
+
+	_, err = New(config.MailConfig{Driver: "unknown"})
+	assert.Error(t, err)
+}
+
+func TestLogMailer(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "mail.log")
+	m := &LogMailer{File: file}
+
+	err := m.Send(context.Background(), Message{
+		To:      []string{"alice@example.com"},
+		Subject: "重置密码",
+		Body:    "reset link",
+	})
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "To: alice@example.com")
+	assert.Contains(t, string(content), "Subject: 重置密码")
+	assert.Contains(t, string(content), "reset link")
+}
+
+func TestBuildMessage(t *testing.T) {
+	raw := string(buildMessage("noreply@example.com", Message{
+		To:      []string{"a@example.com", "b@example.com"},
+		Subject: "重置密码",
+		Body:    "line1\nline2",
+	}))
+
+	assert.Contains(t, raw, "From: noreply@example.com\r\n")
+	assert.Contains(t, raw, "To: a@example.com, b@example.com\r\n")
+	assert.Contains(t, raw, "Subject: =?utf-8?q?")
+	assert.True(t, strings.HasSuffix(raw, "\r\n\r\nline1\r\nline2"))
+}
@@ -0,0 +1,84 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWK 解析 RSA / EC 签名公钥
+func parseJWK(raw json.RawMessage) (string, any, error) {
+	var k jwk
+	if err := json.Unmarshal(raw, &k); err != nil {
+		return "", nil, err
+	}
+	if k.Use != "" && k.Use != "sig" {
+		return "", nil, fmt.Errorf("unsupported key use %q", k.Use)
+	}
+
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return "", nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return "", nil, err
+		}
+		if !e.IsInt64() {
+			return "", nil, errors.New("invalid rsa exponent")
+		}
+		return k.Kid, &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return "", nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return "", nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return "", nil, err
+		}
+		return k.Kid, &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
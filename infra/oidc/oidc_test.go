@@ -0,0 +1,288 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"go-tpl/infra/config"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockIdP 进程内模拟的 OIDC 提供方
+type mockIdP struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	kid    string
+
+	mu    sync.Mutex
+	codes map[string]authRequest // code -> 授权请求
+}
+
+type authRequest struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	idp := &mockIdP{t: t, key: key, kid: "idp-key-1", codes: make(map[string]authRequest)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		pub := idp.key.PublicKey
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": idp.kid,
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", idp.handleToken)
+
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *mockIdP) handleToken(w http.ResponseWriter, r *http.Request) {
+	fail := func(code string) {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": code})
+	}
+
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		fail("invalid_request")
+		return
+	}
+	id, secret, ok := r.BasicAuth()
+	if !ok || id != "go-tpl" || secret != "s3cret" {
+		fail("invalid_client")
+		return
+	}
+
+	idp.mu.Lock()
+	req, ok := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+	idp.mu.Unlock()
+	if !ok || S256Challenge(r.PostForm.Get("code_verifier")) != req.challenge {
+		fail("invalid_grant")
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"access_token": "at",
+		"token_type":   "Bearer",
+		"id_token":     idp.sign(req.claims),
+	})
+}
+
+// authorize 模拟用户在 IdP 完成登录，返回授权码
+func (idp *mockIdP) authorize(authURL string, overrides jwt.MapClaims) string {
+	u, err := url.Parse(authURL)
+	require.NoError(idp.t, err)
+	q := u.Query()
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            idp.server.URL,
+		"sub":            "user-123",
+		"aud":            q.Get("client_id"),
+		"exp":            now.Add(time.Hour).Unix(),
+		"iat":            now.Unix(),
+		"nonce":          q.Get("nonce"),
+		"email":          "alice@example.com",
+		"email_verified": true,
+	}
+	for k, v := range overrides {
+		claims[k] = v
+	}
+
+	code := "code-" + q.Get("state")
+	idp.mu.Lock()
+	idp.codes[code] = authRequest{challenge: q.Get("code_challenge"), claims: claims}
+	idp.mu.Unlock()
+	return code
+}
+
+func (idp *mockIdP) sign(claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = idp.kid
+	signed, err := token.SignedString(idp.key)
+	require.NoError(idp.t, err)
+	return signed
+}
+
+func (idp *mockIdP) provider() *Provider {
+	return NewProvider(config.OIDCProviderConfig{
+		Name:         "corp",
+		Issuer:       idp.server.URL,
+		ClientID:     "go-tpl",
+		ClientSecret: "s3cret",
+		RedirectURL:  "http://localhost:8080/oidc/callback",
+	})
+}
+
+func TestAuthCodeFlow(t *testing.T) {
+	ctx := context.Background()
+	idp := newMockIdP(t)
+	p := idp.provider()
+
+	// login 发起授权，返回授权地址
+	login := func(t *testing.T, state string) (authURL, verifier, nonce string) {
+		verifier, challenge, err := GeneratePKCE()
+		require.NoError(t, err)
+		nonce, err = RandomString(16)
+		require.NoError(t, err)
+
+		authURL, err = p.AuthCodeURL(ctx, state, nonce, challenge)
+		require.NoError(t, err)
+		return authURL, verifier, nonce
+	}
+
+	t.Run("AuthCodeURL", func(t *testing.T) {
+		authURL, _, nonce := login(t, "state-1")
+
+		u, err := url.Parse(authURL)
+		require.NoError(t, err)
+		q := u.Query()
+		assert.Equal(t, idp.server.URL+"/authorize", u.Scheme+"://"+u.Host+u.Path)
+		assert.Equal(t, "code", q.Get("response_type"))
+		assert.Equal(t, "go-tpl", q.Get("client_id"))
+		assert.Equal(t, "openid email profile", q.Get("scope"))
+		assert.Equal(t, "state-1", q.Get("state"))
+		assert.Equal(t, nonce, q.Get("nonce"))
+		assert.Equal(t, "S256", q.Get("code_challenge_method"))
+		assert.NotEmpty(t, q.Get("code_challenge"))
+	})
+
+	t.Run("Exchange", func(t *testing.T) {
+		authURL, verifier, nonce := login(t, "state-2")
+		code := idp.authorize(authURL, nil)
+
+		claims, err := p.Exchange(ctx, code, verifier, nonce)
+		require.NoError(t, err)
+		assert.Equal(t, "user-123", claims.Subject)
+		assert.Equal(t, "alice@example.com", claims.Email)
+		assert.True(t, claims.EmailVerified)
+	})
+
+	t.Run("WrongVerifier", func(t *testing.T) {
+		authURL, _, nonce := login(t, "state-3")
+		code := idp.authorize(authURL, nil)
+
+		_, err := p.Exchange(ctx, code, "wrong-verifier", nonce)
+		assert.ErrorIs(t, err, ErrTokenExchange)
+	})
+
+	t.Run("CodeReuse", func(t *testing.T) {
+		authURL, verifier, nonce := login(t, "state-4")
+		code := idp.authorize(authURL, nil)
+
+		_, err := p.Exchange(ctx, code, verifier, nonce)
+		require.NoError(t, err)
+		_, err = p.Exchange(ctx, code, verifier, nonce)
+		assert.ErrorIs(t, err, ErrTokenExchange)
+	})
+
+	t.Run("NonceMismatch", func(t *testing.T) {
+		authURL, verifier, _ := login(t, "state-5")
+		code := idp.authorize(authURL, nil)
+
+		_, err := p.Exchange(ctx, code, verifier, "other-nonce")
+		assert.ErrorIs(t, err, ErrInvalidIDToken)
+	})
+
+	t.Run("InvalidClaims", func(t *testing.T) {
+		cases := map[string]jwt.MapClaims{
+			"WrongAudience": {"aud": "other-client"},
+			"WrongIssuer":   {"iss": "https://evil.example.com"},
+			"Expired":       {"exp": time.Now().Add(-time.Hour).Unix()},
+			"MissingSub":    {"sub": ""},
+			"AzpMismatch":   {"aud": []string{"go-tpl", "other-client"}, "azp": "other-client"},
+		}
+		for name, overrides := range cases {
+			t.Run(name, func(t *testing.T) {
+				authURL, verifier, nonce := login(t, "state-"+name)
+				code := idp.authorize(authURL, overrides)
+
+				_, err := p.Exchange(ctx, code, verifier, nonce)
+				assert.ErrorIs(t, err, ErrInvalidIDToken)
+			})
+		}
+	})
+
+	t.Run("ForgedSignature", func(t *testing.T) {
+		otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":   idp.server.URL,
+			"sub":   "user-123",
+			"aud":   "go-tpl",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"nonce": "n",
+		})
+		token.Header["kid"] = idp.kid
+		forged, err := token.SignedString(otherKey)
+		require.NoError(t, err)
+
+		_, err = p.VerifyIDToken(ctx, forged, "n")
+		assert.ErrorIs(t, err, ErrInvalidIDToken)
+	})
+
+	t.Run("KeyRotation", func(t *testing.T) {
+		// IdP 轮换签名密钥后，未知 kid 触发重新拉取 JWKS
+		newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		idp.key, idp.kid = newKey, "idp-key-2"
+
+		authURL, verifier, nonce := login(t, "state-rotation")
+		code := idp.authorize(authURL, nil)
+
+		_, err = p.Exchange(ctx, code, verifier, nonce)
+		require.NoError(t, err)
+	})
+}
+
+func TestNew(t *testing.T) {
+	providers, err := New(config.OIDCConfig{Providers: []config.OIDCProviderConfig{
+		{Name: "corp", Issuer: "https://sso.example.com", ClientID: "go-tpl", RedirectURL: "http://localhost/cb"},
+	}})
+	require.NoError(t, err)
+	require.Contains(t, providers, "corp")
+	assert.Equal(t, []string{"openid", "email", "profile"}, providers["corp"].Config().Scopes)
+
+	_, err = New(config.OIDCConfig{Providers: []config.OIDCProviderConfig{{Name: "corp"}}})
+	assert.Error(t, err)
+
+	dup := config.OIDCProviderConfig{Name: "corp", Issuer: "https://sso.example.com", ClientID: "go-tpl", RedirectURL: "http://localhost/cb"}
+	_, err = New(config.OIDCConfig{Providers: []config.OIDCProviderConfig{dup, dup}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,237 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"go-tpl/infra/config"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+var ErrUnknownHash = errors.New("unknown password hash format")
+
+// Algorithm 密码哈希算法，哈希值中自带算法标识和参数
+type Algorithm interface {
+	// Name 算法名称
+	Name() string
+	// Match 判断哈希值是否由该算法生成
+	Match(encoded string) bool
+	// Hash 计算密码哈希
+	Hash(password string) (string, error)
+	// Verify 校验密码
+	Verify(password, encoded string) (bool, error)
+	// NeedsRehash 哈希参数是否弱于当前配置
+	NeedsRehash(encoded string) bool
+}
+
+// Hasher 使用配置的算法生成新哈希，并可校验所有已注册算法生成的哈希
+type Hasher struct {
+	current    Algorithm
+	algorithms []Algorithm
+}
+
+// NewHasher 根据配置创建 Hasher
+func NewHasher(cfg config.PasswordConfig) (*Hasher, error) {
+	bc := &bcryptAlgorithm{cost: cfg.BcryptCost}
+	if bc.cost == 0 {
+		bc.cost = bcrypt.DefaultCost
+	}
+	if bc.cost < bcrypt.MinCost || bc.cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("invalid bcrypt cost %d", bc.cost)
+	}
+
+	ag := &argon2idAlgorithm{params: argon2Params{
+		memory:      cfg.Argon2.Memory,
+		iterations:  cfg.Argon2.Iterations,
+		parallelism: cfg.Argon2.Parallelism,
+		saltLength:  cfg.Argon2.SaltLength,
+		keyLength:   cfg.Argon2.KeyLength,
+	}}
+	ag.params.setDefaults()
+
+	h := &Hasher{algorithms: []Algorithm{ag, bc}}
+	switch cfg.Algorithm {
+	case "", AlgorithmArgon2id:
+		h.current = ag
+	case AlgorithmBcrypt:
+		h.current = bc
+	default:
+		return nil, fmt.Errorf("unsupported password algorithm %q", cfg.Algorithm)
+	}
+	return h, nil
+}
+
+// Hash 使用当前算法计算密码哈希
+func (h *Hasher) Hash(password string) (string, error) {
+	return h.current.Hash(password)
+}
+
+// Verify 校验密码，needsRehash 表示哈希使用了非当前算法或较弱的参数，应在校验成功后重新计算
+func (h *Hasher) Verify(password, encoded string) (ok, needsRehash bool, err error) {
+	for _, alg := range h.algorithms {
+		if !alg.Match(encoded) {
+			continue
+		}
+		ok, err = alg.Verify(password, encoded)
+		if err != nil || !ok {
+			return false, false, err
+		}
+		return true, alg != h.current || alg.NeedsRehash(encoded), nil
+	}
+	return false, false, ErrUnknownHash
+}
+
+// ==================== bcrypt ====================
+
+type bcryptAlgorithm struct {
+	cost int
+}
+
+func (a *bcryptAlgorithm) Name() string {
+	return AlgorithmBcrypt
+}
+
+func (a *bcryptAlgorithm) Match(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+func (a *bcryptAlgorithm) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), a.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (a *bcryptAlgorithm) Verify(password, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (a *bcryptAlgorithm) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost < a.cost
+}
+
+// ==================== argon2id ====================
+
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	saltLength  uint32
+	keyLength   uint32
+}
+
+func (p *argon2Params) setDefaults() {
+	if p.memory == 0 {
+		p.memory = 64 * 1024
+	}
+	if p.iterations == 0 {
+		p.iterations = 3
+	}
+	if p.parallelism == 0 {
+		p.parallelism = 2
+	}
+	if p.saltLength == 0 {
+		p.saltLength = 16
+	}
+	if p.keyLength == 0 {
+		p.keyLength = 32
+	}
+}
+
+type argon2idAlgorithm struct {
+	params argon2Params
+}
+
+func (a *argon2idAlgorithm) Name() string {
+	return AlgorithmArgon2id
+}
+
+func (a *argon2idAlgorithm) Match(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+// Hash 生成 PHC 格式哈希：$argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+func (a *argon2idAlgorithm) Hash(password string) (string, error) {
+	salt := make([]byte, a.params.saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	p := a.params
+	key := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, p.keyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.memory, p.iterations, p.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (a *argon2idAlgorithm) Verify(password, encoded string) (bool, error) {
+	p, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	actual := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, p.keyLength)
+	return subtle.ConstantTimeCompare(actual, key) == 1, nil
+}
+
+func (a *argon2idAlgorithm) NeedsRehash(encoded string) bool {
+	p, _, _, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return p.memory < a.params.memory ||
+		p.iterations < a.params.iterations ||
+		p.parallelism < a.params.parallelism ||
+		p.saltLength < a.params.saltLength ||
+		p.keyLength < a.params.keyLength
+}
+
+func decodeArgon2id(encoded string) (*argon2Params, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", salt, hash
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return nil, nil, nil, ErrUnknownHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return nil, nil, nil, err
+	}
+	if version != argon2.Version {
+		return nil, nil, nil, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+
+	p := &argon2Params{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.iterations, &p.parallelism); err != nil {
+		return nil, nil, nil, err
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	p.saltLength = uint32(len(salt))
+	p.keyLength = uint32(len(key))
+	return p, salt, key, nil
+}
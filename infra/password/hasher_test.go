@@ -0,0 +1,113 @@
+package password
+
+import (
+	"go-tpl/infra/config"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 测试使用较小的 argon2 参数以加快速度
+var testArgon2 = config.Argon2Config{Memory: 8 * 1024, Iterations: 1, Parallelism: 1}
+
+func TestHasher(t *testing.T) {
+	t.Run("Argon2id", func(t *testing.T) {
+		h, err := NewHasher(config.PasswordConfig{Argon2: testArgon2})
+		require.NoError(t, err)
+
+		hash, err := h.Hash("secret123")
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=8192,t=1,p=1$"))
+
+		ok, rehash, err := h.Verify("secret123", hash)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.False(t, rehash)
+
+		ok, _, err = h.Verify("wrong", hash)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("Bcrypt", func(t *testing.T) {
+		h, err := NewHasher(config.PasswordConfig{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost})
+		require.NoError(t, err)
+
+		hash, err := h.Hash("secret123")
+		require.NoError(t, err)
+		cost, err := bcrypt.Cost([]byte(hash))
+		require.NoError(t, err)
+		assert.Equal(t, bcrypt.MinCost, cost)
+
+		ok, rehash, err := h.Verify("secret123", hash)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.False(t, rehash)
+	})
+
+	t.Run("UpgradeAlgorithm", func(t *testing.T) {
+		// 旧的 bcrypt 哈希在切换到 argon2id 后仍可校验，并提示需要重新计算
+		legacy, err := bcrypt.GenerateFromPassword([]byte("secret123"), bcrypt.MinCost)
+		require.NoError(t, err)
+
+		h, err := NewHasher(config.PasswordConfig{Argon2: testArgon2})
+		require.NoError(t, err)
+
+		ok, rehash, err := h.Verify("secret123", string(legacy))
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.True(t, rehash)
+
+		// 密码错误时不提示重新计算
+		ok, rehash, err = h.Verify("wrong", string(legacy))
+		require.NoError(t, err)
+		assert.False(t, ok)
+		assert.False(t, rehash)
+	})
+
+	t.Run("UpgradeParams", func(t *testing.T) {
+		weak, err := NewHasher(config.PasswordConfig{Argon2: testArgon2})
+		require.NoError(t, err)
+		hash, err := weak.Hash("secret123")
+		require.NoError(t, err)
+
+		stronger := testArgon2
+		stronger.Iterations = 2
+		h, err := NewHasher(config.PasswordConfig{Argon2: stronger})
+		require.NoError(t, err)
+
+		ok, rehash, err := h.Verify("secret123", hash)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.True(t, rehash)
+
+		// bcrypt 成本提高后同样需要重新计算
+		low, err := bcrypt.GenerateFromPassword([]byte("secret123"), bcrypt.MinCost)
+		require.NoError(t, err)
+		bh, err := NewHasher(config.PasswordConfig{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost + 1})
+		require.NoError(t, err)
+		ok, rehash, err = bh.Verify("secret123", string(low))
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.True(t, rehash)
+	})
+
+	t.Run("InvalidConfig", func(t *testing.T) {
+		_, err := NewHasher(config.PasswordConfig{Algorithm: "md5"})
+		assert.Error(t, err)
+
+		_, err = NewHasher(config.PasswordConfig{BcryptCost: 64})
+		assert.Error(t, err)
+	})
+
+	t.Run("UnknownHash", func(t *testing.T) {
+		h, err := NewHasher(config.PasswordConfig{Argon2: testArgon2})
+		require.NoError(t, err)
+
+		_, _, err = h.Verify("secret123", "plaintext")
+		assert.ErrorIs(t, err, ErrUnknownHash)
+	})
+}
@@ -0,0 +1,152 @@
+package password
+
+import (
+	"bufio"
+	"fmt"
+	"go-tpl/infra/config"
+	"os"
+	"strings"
+	"unicode"
+)
+
+const (
+	defaultMinLength = 6
+	defaultMaxLength = 72 // bcrypt 仅支持 72 字节，使用 argon2id 时可调大
+
+	minUserInfoLength = 3 // 过短的用户名片段不参与包含检查
+)
+
+// Rule 密码规则
+type Rule string
+
+const (
+	RuleMinLength Rule = "min_length"
+	RuleMaxLength Rule = "max_length"
+	RuleCharClass Rule = "char_class"
+	RuleUserInfo  Rule = "user_info"
+	RuleBanned    Rule = "banned"
+)
+
+// Violation 违反的密码规则
+type Violation struct {
+	Rule   Rule
+	Detail string
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("password violates %s: %s", v.Rule, v.Detail)
+}
+
+// Policy 密码策略
+type Policy struct {
+	cfg    config.PasswordConfig
+	banned map[string]struct{}
+}
+
+// NewPolicy 根据配置创建密码策略，配置了禁用列表时从文件加载
+func NewPolicy(cfg config.PasswordConfig) (*Policy, error) {
+	if cfg.MinLength <= 0 {
+		cfg.MinLength = defaultMinLength
+	}
+	if cfg.MaxLength <= 0 {
+		cfg.MaxLength = defaultMaxLength
+	}
+	if cfg.MinLength > cfg.MaxLength {
+		return nil, fmt.Errorf("password min_length %d exceeds max_length %d", cfg.MinLength, cfg.MaxLength)
+	}
+
+	p := &Policy{cfg: cfg, banned: make(map[string]struct{})}
+	if cfg.BannedFile != "" {
+		if err := p.loadBanned(cfg.BannedFile); err != nil {
+			return nil, fmt.Errorf("load banned passwords failed: %w", err)
+		}
+	}
+	return p, nil
+}
+
+// HistorySize 返回需要检查的历史密码数量
+func (p *Policy) HistorySize() int {
+	return p.cfg.HistorySize
+}
+
+// Validate 校验密码，userInfo 为用户名、邮箱等不允许出现在密码中的信息
+// 违反规则时返回 *Violation
+func (p *Policy) Validate(password string, userInfo ...string) error {
+	if len([]rune(password)) < p.cfg.MinLength {
+		return &Violation{Rule: RuleMinLength, Detail: fmt.Sprintf("至少 %d 位", p.cfg.MinLength)}
+	}
+	if len(password) > p.cfg.MaxLength {
+		return &Violation{Rule: RuleMaxLength, Detail: fmt.Sprintf("最多 %d 个字节", p.cfg.MaxLength)}
+	}
+
+	if missing := p.missingClasses(password); len(missing) > 0 {
+		return &Violation{Rule: RuleCharClass, Detail: strings.Join(missing, "、")}
+	}
+
+	if p.cfg.DisallowUserInfo {
+		lower := strings.ToLower(password)
+		for _, info := range userInfo {
+			// 邮箱只检查 @ 之前的部分
+			info, _, _ = strings.Cut(strings.ToLower(info), "@")
+			if len(info) >= minUserInfoLength && strings.Contains(lower, info) {
+				return &Violation{Rule: RuleUserInfo, Detail: "不能包含用户名或邮箱"}
+			}
+		}
+	}
+
+	if _, ok := p.banned[strings.ToLower(password)]; ok {
+		return &Violation{Rule: RuleBanned, Detail: "密码过于常见"}
+	}
+	return nil
+}
+
+// missingClasses 返回缺少的字符类别
+func (p *Policy) missingClasses(password string) []string {
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+
+	var missing []string
+	if p.cfg.RequireUpper && !upper {
+		missing = append(missing, "大写字母")
+	}
+	if p.cfg.RequireLower && !lower {
+		missing = append(missing, "小写字母")
+	}
+	if p.cfg.RequireDigit && !digit {
+		missing = append(missing, "数字")
+	}
+	if p.cfg.RequireSymbol && !symbol {
+		missing = append(missing, "特殊字符")
+	}
+	return missing
+}
+
+// loadBanned 加载禁用密码列表，忽略空行和 # 开头的注释
+func (p *Policy) loadBanned(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.banned[strings.ToLower(line)] = struct{}{}
+	}
+	return scanner.Err()
+}
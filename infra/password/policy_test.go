@@ -0,0 +1,82 @@
+package password
+
+import (
+	"go-tpl/infra/config"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func assertViolation(t *testing.T, err error, rule Rule) {
+	t.Helper()
+	var v *Violation
+	require.ErrorAs(t, err, &v)
+	assert.Equal(t, rule, v.Rule)
+}
+
+func TestPolicy(t *testing.T) {
+	banned := filepath.Join(t.TempDir(), "banned.txt")
+	require.NoError(t, os.WriteFile(banned, []byte("# 常见密码\nPassword123\n\nqwerty123\n"), 0644))
+
+	p, err := NewPolicy(config.PasswordConfig{
+		MinLength:        8,
+		RequireUpper:     true,
+		RequireLower:     true,
+		RequireDigit:     true,
+		DisallowUserInfo: true,
+		BannedFile:       banned,
+	})
+	require.NoError(t, err)
+
+	t.Run("Valid", func(t *testing.T) {
+		assert.NoError(t, p.Validate("Str0ngPass", "alice", "alice@example.com"))
+	})
+
+	t.Run("MinLength", func(t *testing.T) {
+		assertViolation(t, p.Validate("Ab1"), RuleMinLength)
+	})
+
+	t.Run("MaxLength", func(t *testing.T) {
+		long := make([]byte, 73)
+		for i := range long {
+			long[i] = 'a'
+		}
+		assertViolation(t, p.Validate("A1"+string(long)), RuleMaxLength)
+	})
+
+	t.Run("CharClass", func(t *testing.T) {
+		err := p.Validate("alllowercase1")
+		assertViolation(t, err, RuleCharClass)
+		assert.Contains(t, err.(*Violation).Detail, "大写字母")
+	})
+
+	t.Run("UserInfo", func(t *testing.T) {
+		assertViolation(t, p.Validate("xxAlice2024X", "alice"), RuleUserInfo)
+		assertViolation(t, p.Validate("Bob.smith99", "bob", "bob.smith@example.com"), RuleUserInfo)
+		// 过短的用户名不参与检查
+		assert.NoError(t, p.Validate("Jo1234567", "jo"))
+	})
+
+	t.Run("Banned", func(t *testing.T) {
+		assertViolation(t, p.Validate("Password123"), RuleBanned)
+		// 不区分大小写
+		assertViolation(t, p.Validate("pASSWORD123"), RuleBanned)
+	})
+}
+
+func TestPolicyDefaults(t *testing.T) {
+	p, err := NewPolicy(config.PasswordConfig{})
+	require.NoError(t, err)
+
+	assert.NoError(t, p.Validate("abcdef"))
+	assertViolation(t, p.Validate("abcde"), RuleMinLength)
+
+	_, err = NewPolicy(config.PasswordConfig{MinLength: 80, MaxLength: 72})
+	assert.Error(t, err)
+
+	_, err = NewPolicy(config.PasswordConfig{BannedFile: filepath.Join(t.TempDir(), "missing.txt")})
+	assert.Error(t, err)
+}
@@ -0,0 +1,67 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCode(t *testing.T) {
+	// RFC 6238 附录 B 的 SHA1 测试向量（取后 6 位）
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	cases := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, c := range cases {
+		code, err := Code(secret, Step(time.Unix(c.unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, c.code, code, "unix=%d", c.unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+
+	now := time.Now()
+	code, err := Code(secret, Step(now))
+	require.NoError(t, err)
+
+	t.Run("CurrentStep", func(t *testing.T) {
+		step, ok := Validate(secret, code, now, 1)
+		assert.True(t, ok)
+		assert.Equal(t, Step(now), step)
+	})
+
+	t.Run("ClockSkew", func(t *testing.T) {
+		_, ok := Validate(secret, code, now.Add(Period*time.Second), 1)
+		assert.True(t, ok)
+
+		_, ok = Validate(secret, code, now.Add(3*Period*time.Second), 1)
+		assert.False(t, ok)
+	})
+
+	t.Run("InvalidCode", func(t *testing.T) {
+		_, ok := Validate(secret, "abc", now, 1)
+		assert.False(t, ok)
+	})
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("go-tpl", "alice", "JBSWY3DPEHPK3PXP")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/go-tpl:alice?"))
+	assert.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(t, uri, "issuer=go-tpl")
+}
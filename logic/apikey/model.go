@@ -0,0 +1,32 @@
+package apikey
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKey 个人访问令牌，仅保存哈希
+type APIKey struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	UserID      uint           `gorm:"index;not null" json:"user_id"`
+	Name        string         `gorm:"size:100;not null" json:"name"`
+	Prefix      string         `gorm:"size:16;not null" json:"prefix"` // 明文前缀，便于识别
+	KeyHash     string         `gorm:"uniqueIndex;size:64;not null" json:"-"`
+	Permissions []string       `gorm:"serializer:json;type:text" json:"permissions"`
+	ExpiresAt   *time.Time     `json:"expires_at"`
+	LastUsedAt  *time.Time     `json:"last_used_at"`
+	LastUsedIP  string         `gorm:"size:45" json:"last_used_ip"`
+	CreatedAt   time.Time      `json:"created_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// CreatedAPIKey 新建的 API Key，明文仅在创建时返回一次
+type CreatedAPIKey struct {
+	*APIKey
+	Key string `json:"key"`
+}
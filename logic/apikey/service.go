@@ -0,0 +1,167 @@
+package apikey
+
+import (
+	"context"
+	"fmt"
+	"go-tpl/logic/permission"
+	"go-tpl/logic/shared"
+	"go-tpl/logic/user"
+	"go-tpl/web/types"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	// KeyPrefix API Key 明文前缀，用于与 JWT 区分
+	KeyPrefix = "pat_"
+
+	displayPrefixLength = 12
+	touchInterval       = time.Minute
+)
+
+type Service struct {
+	db            *gorm.DB
+	redis         *redis.Client
+	userSvc       *user.Service
+	permissionSvc *permission.Service
+}
+
+func NewService(db *gorm.DB, redis *redis.Client, userSvc *user.Service, permissionSvc *permission.Service) *Service {
+	return &Service{
+		db:            db,
+		redis:         redis,
+		userSvc:       userSvc,
+		permissionSvc: permissionSvc,
+	}
+}
+
+// IsAPIKey 判断凭证是否为 API Key
+func IsAPIKey(credential string) bool {
+	return strings.HasPrefix(credential, KeyPrefix)
+}
+
+// Create 创建 API Key，权限必须被所属用户当前权限覆盖（可使用通配符，如 user:*）
+func (s *Service) Create(ctx context.Context, userID uint, req types.CreateAPIKeyReq) (*CreatedAPIKey, error) {
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, shared.ErrInvalidParam
+	}
+
+	owned, err := s.permissionSvc.GetUserPermissions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var invalid []string
+	permissions := make([]string, 0, len(req.Permissions))
+	for _, code := range req.Permissions {
+		if slices.Contains(permissions, code) {
+			continue
+		}
+		if permission.ValidateCode(code) != nil || !permission.MatchAny(owned, code) {
+			invalid = append(invalid, code)
+			continue
+		}
+		permissions = append(permissions, code)
+	}
+	if len(invalid) > 0 {
+		return nil, shared.ErrAPIKeyScope.WithDetail(strings.Join(invalid, ", "))
+	}
+
+	token, err := shared.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	key := KeyPrefix + token
+
+	apiKey := &APIKey{
+		UserID:      userID,
+		Name:        req.Name,
+		Prefix:      key[:displayPrefixLength],
+		KeyHash:     shared.HashToken(key),
+		Permissions: permissions,
+		ExpiresAt:   req.ExpiresAt,
+	}
+	if err = s.db.WithContext(ctx).Create(apiKey).Error; err != nil {
+		return nil, err
+	}
+
+	return &CreatedAPIKey{APIKey: apiKey, Key: key}, nil
+}
+
+// List 获取用户的 API Key 列表
+func (s *Service) List(ctx context.Context, userID uint) ([]APIKey, error) {
+	var list []APIKey
+	if err := s.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("id DESC").
+		Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Delete 吊销用户的 API Key
+func (s *Service) Delete(ctx context.Context, userID, id uint) error {
+	result := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&APIKey{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return shared.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Authenticate 校验 API Key，返回对应记录并更新最近使用信息
+func (s *Service) Authenticate(ctx context.Context, key, ip string) (*APIKey, error) {
+	var apiKey APIKey
+	result := s.db.WithContext(ctx).Where("key_hash = ?", shared.HashToken(key)).Limit(1).Find(&apiKey)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, shared.ErrAPIKeyInvalid
+	}
+	if apiKey.ExpiresAt != nil && apiKey.ExpiresAt.Before(time.Now()) {
+		return nil, shared.ErrAPIKeyInvalid
+	}
+
+	// 所属用户被禁用或删除后 API Key 随之失效
+	owner, err := s.userSvc.Get(ctx, apiKey.UserID)
+	if err != nil {
+		return nil, shared.ErrAPIKeyInvalid
+	}
+	if owner.Status != shared.StatusActive {
+		return nil, shared.ErrUserDisabled
+	}
+
+	if err = s.touch(ctx, apiKey.ID, ip); err != nil {
+		return nil, err
+	}
+	return &apiKey, nil
+}
+
+// HasPermission 检查 API Key 是否拥有指定权限，须同时在 Key 的权限范围和所属用户的当前权限内
+func (s *Service) HasPermission(ctx context.Context, apiKey *APIKey, code string) (bool, error) {
+	if !permission.MatchAny(apiKey.Permissions, code) {
+		return false, nil
+	}
+	return s.permissionSvc.HasPermission(ctx, apiKey.UserID, code)
+}
+
+// touch 更新最近使用时间和 IP，同一 Key 每分钟最多写一次数据库
+func (s *Service) touch(ctx context.Context, id uint, ip string) error {
+	ok, err := s.redis.SetNX(ctx, fmt.Sprintf(shared.CacheAPIKeyTouch, id), 1, touchInterval).Result()
+	if err != nil || !ok {
+		return err
+	}
+
+	return s.db.WithContext(ctx).Model(&APIKey{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_used_at": time.Now(),
+		"last_used_ip": ip,
+	}).Error
+}
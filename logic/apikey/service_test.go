@@ -0,0 +1,134 @@
+package apikey
+
+import (
+	"context"
+	"fmt"
+	"go-tpl/logic/permission"
+	"go-tpl/logic/shared"
+	"go-tpl/logic/user"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func setupTestService(t *testing.T) (*Service, sqlmock.Sqlmock, *miniredis.Miniredis) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{Conn: db, SkipInitializeWithVersion: true}), &gorm.Config{})
+	require.NoError(t, err)
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	return NewService(gormDB, rdb, user.NewService(gormDB, rdb, nil, nil, nil), permission.NewService(gormDB, rdb, nil)), mock, mr
+}
+
+func TestAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	key := KeyPrefix + "secret"
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	keyRows := func(expiresAt *time.Time) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "user_id", "key_hash", "permissions", "expires_at"}).
+			AddRow(3, 7, shared.HashToken(key), `["user:read"]`, expiresAt)
+	}
+	ownerRows := func(status int8) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "username", "status"}).AddRow(7, "alice", status)
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		s, mock, mr := setupTestService(t)
+		expectValid := func() {
+			mock.ExpectQuery("SELECT \\* FROM `api_keys`").WithArgs(shared.HashToken(key), 1).WillReturnRows(keyRows(&future))
+			mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(ownerRows(shared.StatusActive))
+		}
+		// 同一 Key 每分钟最多更新一次最近使用信息
+		expectValid()
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE `api_keys` SET").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		expectValid()
+
+		for range 2 {
+			apiKey, err := s.Authenticate(ctx, key, "10.0.0.1")
+			require.NoError(t, err)
+			assert.Equal(t, uint(7), apiKey.UserID)
+			assert.Equal(t, []string{"user:read"}, apiKey.Permissions)
+		}
+		require.NoError(t, mock.ExpectationsWereMet())
+		assert.True(t, mr.Exists(fmt.Sprintf(shared.CacheAPIKeyTouch, 3)))
+	})
+
+	cases := []struct {
+		name   string
+		expect func(mock sqlmock.Sqlmock)
+		want   error
+	}{
+		{"unknown key", func(mock sqlmock.Sqlmock) {
+			mock.ExpectQuery("SELECT \\* FROM `api_keys`").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		}, shared.ErrAPIKeyInvalid},
+		{"expired", func(mock sqlmock.Sqlmock) {
+			mock.ExpectQuery("SELECT \\* FROM `api_keys`").WillReturnRows(keyRows(&past))
+		}, shared.ErrAPIKeyInvalid},
+		{"owner deleted", func(mock sqlmock.Sqlmock) {
+			mock.ExpectQuery("SELECT \\* FROM `api_keys`").WillReturnRows(keyRows(nil))
+			mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		}, shared.ErrAPIKeyInvalid},
+		{"owner disabled", func(mock sqlmock.Sqlmock) {
+			mock.ExpectQuery("SELECT \\* FROM `api_keys`").WillReturnRows(keyRows(nil))
+			mock.ExpectQuery("SELECT \\* FROM `users`").WillReturnRows(ownerRows(shared.StatusDisabled))
+		}, shared.ErrUserDisabled},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s, mock, mr := setupTestService(t)
+			c.expect(mock)
+
+			_, err := s.Authenticate(ctx, key, "10.0.0.1")
+			assert.ErrorIs(t, err, c.want)
+			require.NoError(t, mock.ExpectationsWereMet())
+			assert.False(t, mr.Exists(fmt.Sprintf(shared.CacheAPIKeyTouch, 3)))
+		})
+	}
+}
+
+func TestHasPermission(t *testing.T) {
+	cases := []struct {
+		name       string
+		keyScope   []string
+		userGrants string
+		ip         string
+		code       string
+		want       bool
+	}{
+		{"in scope", []string{"user:read"}, `[{"code":"*"}]`, "", "user:read", true},
+		{"outside key scope", []string{"user:read"}, `[{"code":"*"}]`, "", "user:update", false},
+		{"wildcard scope", []string{"user:*"}, `[{"code":"*"}]`, "", "user:update", true},
+		// Key 的权限范围不能超出所属用户的当前权限
+		{"owner lacks permission", []string{"user:*"}, `[{"code":"user:read"}]`, "", "user:update", false},
+		{"owner has no grants", []string{"user:read"}, `[]`, "", "user:read", false},
+		// 所属用户权限的访问条件同样生效
+		{"owner condition satisfied", []string{"user:read"}, `[{"code":"user:read","conditions":["cidr(ip, '10.0.0.0/8')"]}]`, "10.0.0.1", "user:read", true},
+		{"owner condition unsatisfied", []string{"user:read"}, `[{"code":"user:read","conditions":["cidr(ip, '10.0.0.0/8')"]}]`, "192.168.0.1", "user:read", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s, _, mr := setupTestService(t)
+			// 缓存版本均为 0，所属用户的权限直接取自缓存
+			require.NoError(t, mr.Set(fmt.Sprintf(shared.CachePermissionUser, 7), `{"v":0,"uv":0,"grants":`+c.userGrants+`}`))
+			require.NoError(t, mr.Set(shared.CachePermissionConditions, `{"v":0,"conditions":{}}`))
+
+			ctx := permission.WithAttributes(context.Background(), permission.Attributes{IP: c.ip})
+			ok, err := s.HasPermission(ctx, &APIKey{UserID: 7, Permissions: c.keyScope}, c.code)
+			require.NoError(t, err)
+			assert.Equal(t, c.want, ok)
+		})
+	}
+}
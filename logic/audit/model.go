@@ -0,0 +1,24 @@
+package audit
+
+import "time"
+
+const (
+	ActionImpersonate = "user.impersonate" // 模拟登录
+)
+
+// Log 审计记录，只增不改
+type Log struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	ActorID   uint           `gorm:"index;not null" json:"actor_id"` // 实际操作人
+	Action    string         `gorm:"size:64;not null" json:"action"`
+	TargetID  uint           `gorm:"index" json:"target_id"` // 被操作的用户
+	Reason    string         `gorm:"size:255" json:"reason"`
+	IP        string         `gorm:"size:45" json:"ip"`
+	UserAgent string         `gorm:"size:255" json:"user_agent"`
+	Detail    map[string]any `gorm:"serializer:json;type:text" json:"detail"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+func (Log) TableName() string {
+	return "audit_logs"
+}
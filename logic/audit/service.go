@@ -0,0 +1,24 @@
+package audit
+
+import (
+	"context"
+	"go-tpl/logic/shared"
+
+	"gorm.io/gorm"
+)
+
+type Service struct {
+	db *gorm.DB
+}
+
+func NewService(db *gorm.DB) *Service {
+	return &Service{
+		db: db,
+	}
+}
+
+// Record 写入审计记录
+func (s *Service) Record(ctx context.Context, log *Log) error {
+	log.UserAgent = shared.Truncate(log.UserAgent, shared.MaxUserAgentLength)
+	return s.db.WithContext(ctx).Create(log).Error
+}
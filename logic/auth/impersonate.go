@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"go-tpl/infra/jwt"
+	"go-tpl/infra/logger"
+	"go-tpl/logic/audit"
+	"go-tpl/logic/permission"
+	"go-tpl/logic/shared"
+	"go-tpl/web/types"
+	"strings"
+)
+
+// Impersonate 签发以 actorID 身份模拟 userID 的短期 access token，并写入审计记录
+func (s *Service) Impersonate(ctx context.Context, actorID, userID uint, req types.ImpersonateReq, client types.ClientInfo) (*jwt.ImpersonationToken, error) {
+	if actorID == userID {
+		return nil, shared.ErrImpersonateSelf
+	}
+
+	target, err := s.userSvc.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if target.Status != shared.StatusActive {
+		return nil, shared.ErrUserDisabled
+	}
+
+	// 管理员不允许被模拟；附带访问条件的管理员权限同样视为管理员，不按发起者的请求属性求值
+	isAdmin, err := s.permissionSvc.HasGrantedCode(ctx, userID, permission.CodeAdminAccess)
+	if err != nil {
+		return nil, err
+	}
+	if isAdmin {
+		return nil, shared.ErrImpersonateAdmin
+	}
+
+	// 被模拟用户的权限须全部被发起者的权限覆盖，避免借助其身份获得额外权限（如为自己分配角色）
+	uncovered, err := s.permissionSvc.UncoveredGrants(ctx, actorID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(uncovered) > 0 {
+		return nil, shared.ErrImpersonateScope.WithDetail(strings.Join(uncovered, ","))
+	}
+
+	token, err := jwt.GenerateImpersonationToken(userID, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 审计记录写入失败时不返回 token
+	err = s.auditSvc.Record(ctx, &audit.Log{
+		ActorID:   actorID,
+		Action:    audit.ActionImpersonate,
+		TargetID:  userID,
+		Reason:    req.Reason,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+		Detail: map[string]any{
+			"token_id":   token.TokenID,
+			"expires_at": token.ExpiresAt,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Warn(ctx, "impersonation token issued",
+		logger.Int("actor_id", int(actorID)),
+		logger.Int("user_id", int(userID)),
+		logger.Str("reason", req.Reason))
+	return token, nil
+}
@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-tpl/infra/jwt"
+	"go-tpl/infra/logger"
+	"go-tpl/infra/oidc"
+	"go-tpl/logic/shared"
+	"go-tpl/logic/user"
+	"go-tpl/web/types"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const oidcStateTTL = 10 * time.Minute
+
+// oidcState 授权请求上下文，以 state 为键保存在 Redis 中
+type oidcState struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	BindingHash  string `json:"binding_hash"` // 发起授权的浏览器持有的绑定值的哈希
+}
+
+// OIDCProviders 获取已配置的第三方登录方式
+func (s *Service) OIDCProviders() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OIDCAuthorize 发起授权码 + PKCE 流程，返回 IdP 授权地址，以及须写入发起浏览器 Cookie 的绑定值，
+// 回调时校验绑定值，防止攻击者将自己的授权码注入他人浏览器（登录 CSRF）
+func (s *Service) OIDCAuthorize(ctx context.Context, providerName string) (*types.OIDCAuthorizeResp, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, shared.ErrOIDCProviderNotFound
+	}
+
+	state, err := oidc.RandomString(32)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := oidc.RandomString(32)
+	if err != nil {
+		return nil, err
+	}
+	binding, err := oidc.RandomString(32)
+	if err != nil {
+		return nil, err
+	}
+	verifier, challenge, err := oidc.GeneratePKCE()
+	if err != nil {
+		return nil, err
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := json.Marshal(oidcState{
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		BindingHash:  shared.HashToken(binding),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = s.redis.Set(ctx, fmt.Sprintf(shared.CacheOIDCState, state), value, oidcStateTTL).Err(); err != nil {
+		return nil, err
+	}
+
+	return &types.OIDCAuthorizeResp{AuthURL: authURL, State: state, Binding: binding}, nil
+}
+
+// OIDCCallback 使用授权码完成登录：校验 state 及其浏览器绑定和 ID token，按外部身份或已验证邮箱关联用户，必要时自动创建；
+// 开启两步验证的用户与密码登录一样返回挑战 token
+func (s *Service) OIDCCallback(ctx context.Context, providerName string, req types.OIDCCallbackReq, binding string, client types.ClientInfo) (*types.LoginResp, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, shared.ErrOIDCProviderNotFound
+	}
+
+	// state 仅可使用一次
+	value, err := s.redis.GetDel(ctx, fmt.Sprintf(shared.CacheOIDCState, req.State)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, shared.ErrOIDCStateInvalid
+		}
+		return nil, err
+	}
+	var state oidcState
+	if err = json.Unmarshal([]byte(value), &state); err != nil || state.Provider != providerName {
+		return nil, shared.ErrOIDCStateInvalid
+	}
+	if binding == "" || subtle.ConstantTimeCompare([]byte(shared.HashToken(binding)), []byte(state.BindingHash)) != 1 {
+		return nil, shared.ErrOIDCStateInvalid
+	}
+
+	claims, err := provider.Exchange(ctx, req.Code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		logger.Warn(ctx, "oidc login failed", logger.Str("provider", providerName), logger.Str("error", err.Error()))
+		return nil, shared.ErrOIDCLoginFailed
+	}
+
+	loginUser, err := s.resolveOIDCUser(ctx, provider, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	// 需要两步验证
+	if loginUser.TwoFactorEnabled {
+		challengeToken, err := jwt.GenerateChallengeToken(loginUser.ID)
+		if err != nil {
+			return nil, errors.New("生成token失败: " + err.Error())
+		}
+		return &types.LoginResp{TwoFactorRequired: true, ChallengeToken: challengeToken}, nil
+	}
+
+	tokenPair, err := s.issueTokenPair(ctx, loginUser.ID, client)
+	if err != nil {
+		return nil, err
+	}
+	return &types.LoginResp{TokenPair: tokenPair}, nil
+}
+
+// resolveOIDCUser 查找外部身份绑定的用户，未绑定时按已验证邮箱关联或自动创建
+func (s *Service) resolveOIDCUser(ctx context.Context, provider *oidc.Provider, claims *oidc.IDTokenClaims) (*user.User, error) {
+	cfg := provider.Config()
+
+	u, err := s.userSvc.GetByIdentity(ctx, cfg.Name, claims.Subject)
+	if err != nil && !errors.Is(err, shared.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if u == nil {
+		// 仅信任 IdP 已验证的邮箱，避免通过未验证邮箱接管本地账户
+		if claims.Email == "" || !claims.EmailVerified {
+			return nil, shared.ErrOIDCEmailNotVerified
+		}
+		email := strings.ToLower(claims.Email)
+
+		u, err = s.userSvc.GetByEmail(ctx, email)
+		switch {
+		case errors.Is(err, shared.ErrRecordNotFound):
+			if !cfg.AutoProvision {
+				return nil, shared.ErrOIDCUserNotFound
+			}
+			u, err = s.userSvc.ProvisionExternalUser(ctx, email, claims.PreferredUsername)
+		case err == nil && u.Status == shared.StatusPending:
+			// 未验证邮箱的本地账户可能由他人抢注，绑定前重置其密码
+			err = s.userSvc.ResetRandomPassword(ctx, u.ID)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if err = s.userSvc.LinkIdentity(ctx, u.ID, cfg.Name, claims.Subject, email); err != nil {
+			return nil, err
+		}
+		logger.Info(ctx, "oidc identity linked",
+			logger.Str("provider", cfg.Name),
+			logger.Int("user_id", int(u.ID)))
+	}
+
+	switch u.Status {
+	case shared.StatusActive:
+	case shared.StatusPending:
+		// IdP 已验证邮箱，视为完成邮箱验证
+		if err = s.userSvc.VerifyEmail(ctx, u.ID, u.Email); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, shared.ErrUserDisabled
+	}
+	return u, nil
+}
@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-tpl/infra"
+	"go-tpl/infra/logger"
+	"go-tpl/infra/mail"
+	"go-tpl/logic/shared"
+	"go-tpl/web/types"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	passwordResetTTL      = 30 * time.Minute
+	passwordResetCooldown = time.Minute
+)
+
+// ForgotPassword 发送密码重置邮件，邮箱不存在时同样返回成功，避免泄露账户信息
+func (s *Service) ForgotPassword(ctx context.Context, req types.ForgotPasswordReq) error {
+	u, err := s.userSvc.GetByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, shared.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if u.Status != shared.StatusActive {
+		return nil
+	}
+
+	// 发送频率限制
+	ok, err := s.redis.SetNX(ctx, fmt.Sprintf(shared.CachePasswordResetCooldown, u.ID), 1, passwordResetCooldown).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+
+	token, err := shared.RandomToken(32)
+	if err != nil {
+		return err
+	}
+	tokenHash := shared.HashToken(token)
+
+	// 使之前的重置链接失效，每个用户同时只有一个有效 token
+	userKey := fmt.Sprintf(shared.CachePasswordResetUser, u.ID)
+	prev, err := s.redis.Get(ctx, userKey).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+	if err == nil {
+		if err = s.redis.Del(ctx, fmt.Sprintf(shared.CachePasswordResetToken, prev)).Err(); err != nil {
+			return err
+		}
+	}
+
+	_, err = s.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, fmt.Sprintf(shared.CachePasswordResetToken, tokenHash), u.ID, passwordResetTTL)
+		pipe.Set(ctx, userKey, tokenHash, passwordResetTTL)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	link := infra.Cfg.Server.PublicURL + "/reset-password?token=" + url.QueryEscape(token)
+	err = s.mailer.Send(ctx, mail.Message{
+		To:      []string{u.Email},
+		Subject: "重置密码",
+		Body: fmt.Sprintf("%s，您好：\n\n请在 %d 分钟内点击以下链接重置密码：\n%s\n\n如果不是您本人操作，请忽略此邮件。",
+			u.Username, int(passwordResetTTL.Minutes()), link),
+	})
+	if err != nil {
+		// 不向调用方暴露发送结果
+		logger.Errw(ctx, err, logger.Int("user_id", int(u.ID)))
+	}
+	return nil
+}
+
+// ResetPassword 使用一次性 token 重置密码，并吊销该用户已签发的 token
+func (s *Service) ResetPassword(ctx context.Context, req types.ResetPasswordReq) error {
+	tokenKey := fmt.Sprintf(shared.CachePasswordResetToken, shared.HashToken(req.Token))
+
+	value, err := s.redis.Get(ctx, tokenKey).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return shared.ErrResetTokenInvalid
+		}
+		return err
+	}
+	userID, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return shared.ErrResetTokenInvalid
+	}
+
+	// 先校验新密码再消耗 token，新密码不符合策略或与历史密码相同时链接仍可继续使用
+	if err = s.userSvc.ValidateNewPassword(ctx, uint(userID), req.Password); err != nil {
+		if errors.Is(err, shared.ErrRecordNotFound) {
+			return shared.ErrResetTokenInvalid
+		}
+		return err
+	}
+
+	// GETDEL 保证 token 只能使用一次，校验期间已被并发使用时同样视为无效
+	if err = s.redis.GetDel(ctx, tokenKey).Err(); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return shared.ErrResetTokenInvalid
+		}
+		return err
+	}
+
+	if err = s.redis.Del(ctx, fmt.Sprintf(shared.CachePasswordResetUser, userID)).Err(); err != nil {
+		return err
+	}
+
+	// 更新密码会同时吊销该用户的所有 token
+	if err = s.userSvc.Update(ctx, uint(userID), types.UpdateUserReq{Password: req.Password}); err != nil {
+		return err
+	}
+
+	// 重置成功后解除登录锁定
+	return s.userSvc.Unlock(ctx, uint(userID))
+}
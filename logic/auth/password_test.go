@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"go-tpl/infra/config"
+	pwd "go-tpl/infra/password"
+	"go-tpl/logic/shared"
+	"go-tpl/logic/user"
+	"go-tpl/web/types"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func TestResetPasswordKeepsTokenOnPolicyError(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{Conn: db, SkipInitializeWithVersion: true}), &gorm.Config{})
+	require.NoError(t, err)
+
+	cfg := config.PasswordConfig{MinLength: 8, HistorySize: 3, Algorithm: pwd.AlgorithmBcrypt, BcryptCost: bcrypt.MinCost}
+	policy, err := pwd.NewPolicy(cfg)
+	require.NoError(t, err)
+	hasher, err := pwd.NewHasher(cfg)
+	require.NoError(t, err)
+	currentHash, err := hasher.Hash("Current#2026")
+	require.NoError(t, err)
+	oldHash, err := hasher.Hash("Previous#2025")
+	require.NoError(t, err)
+
+	s := NewService(user.NewService(gormDB, rdb, policy, hasher, nil), nil, nil, rdb, nil, nil)
+
+	token := "reset-token"
+	tokenKey := fmt.Sprintf(shared.CachePasswordResetToken, shared.HashToken(token))
+	userKey := fmt.Sprintf(shared.CachePasswordResetUser, 7)
+	require.NoError(t, mr.Set(tokenKey, "7"))
+	require.NoError(t, mr.Set(userKey, shared.HashToken(token)))
+
+	expectUser := func() {
+		mock.ExpectQuery("SELECT \\* FROM `users`").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "password", "status"}).
+				AddRow(7, "alice", "alice@example.com", currentHash, shared.StatusActive))
+	}
+
+	cases := []struct {
+		name     string
+		password string
+		history  bool
+		want     shared.Error
+	}{
+		{"too short", "short", false, shared.ErrPasswordTooShort},
+		{"current password", "Current#2026", true, shared.ErrPasswordReused},
+		{"recent password", "Previous#2025", true, shared.ErrPasswordReused},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			expectUser()
+			if c.history {
+				mock.ExpectQuery("SELECT `password_hash` FROM `user_password_histories`").
+					WillReturnRows(sqlmock.NewRows([]string{"password_hash"}).AddRow(oldHash))
+			}
+
+			err := s.ResetPassword(ctx, types.ResetPasswordReq{Token: token, Password: c.password})
+			var e shared.Error
+			require.ErrorAs(t, err, &e)
+			assert.Equal(t, c.want.Code, e.Code)
+
+			// 密码不合规时重置链接仍然有效
+			assert.True(t, mr.Exists(tokenKey))
+			assert.True(t, mr.Exists(userKey))
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+
+	t.Run("invalid token", func(t *testing.T) {
+		err := s.ResetPassword(ctx, types.ResetPasswordReq{Token: "unknown", Password: "Another#2027"})
+		assert.ErrorIs(t, err, shared.ErrResetTokenInvalid)
+	})
+}
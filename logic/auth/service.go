@@ -3,23 +3,42 @@ package auth
 import (
 	"context"
 	"errors"
+	"go-tpl/infra"
 	"go-tpl/infra/jwt"
+	"go-tpl/infra/logger"
+	"go-tpl/infra/mail"
+	"go-tpl/infra/oidc"
+	"go-tpl/logic/audit"
+	"go-tpl/logic/permission"
+	"go-tpl/logic/shared"
 	"go-tpl/logic/user"
 	"go-tpl/web/types"
+
+	"github.com/redis/go-redis/v9"
 )
 
 type Service struct {
-	userSvc *user.Service
+	userSvc       *user.Service
+	permissionSvc *permission.Service
+	auditSvc      *audit.Service
+	redis         *redis.Client
+	mailer        mail.Mailer
+	providers     oidc.Providers
 }
 
-func NewService(userSvc *user.Service) *Service {
+func NewService(userSvc *user.Service, permissionSvc *permission.Service, auditSvc *audit.Service, redis *redis.Client, mailer mail.Mailer, providers oidc.Providers) *Service {
 	return &Service{
-		userSvc: userSvc,
+		userSvc:       userSvc,
+		permissionSvc: permissionSvc,
+		auditSvc:      auditSvc,
+		redis:         redis,
+		mailer:        mailer,
+		providers:     providers,
 	}
 }
 
-// Register 用户注册
-func (s *Service) Register(ctx context.Context, req types.RegisterReq) (*jwt.TokenPair, error) {
+// Register 用户注册，开启邮箱验证时创建待验证用户并发送验证邮件，不返回 token
+func (s *Service) Register(ctx context.Context, req types.RegisterReq, client types.ClientInfo) (*types.RegisterResp, error) {
 	// 创建用户
 	userCreateReq := types.CreateUserReq{
 		Username: req.Username,
@@ -27,43 +46,126 @@ func (s *Service) Register(ctx context.Context, req types.RegisterReq) (*jwt.Tok
 		Password: req.Password,
 	}
 
+	if infra.Cfg.Register.VerifyEmail {
+		createdUser, err := s.userSvc.CreatePending(ctx, userCreateReq)
+		if err != nil {
+			return nil, err
+		}
+		if err = s.sendVerificationEmail(ctx, createdUser, createdUser.Email); err != nil {
+			// 用户可通过重发接口再次获取验证邮件
+			logger.Errw(ctx, err, logger.Int("user_id", int(createdUser.ID)))
+		}
+		return &types.RegisterResp{VerificationRequired: true}, nil
+	}
+
 	createdUser, err := s.userSvc.Create(ctx, userCreateReq)
 	if err != nil {
 		return nil, err
 	}
 
-	// 生成token对
-	tokenPair, err := jwt.GenerateTokenPair(createdUser.ID)
+	tokenPair, err := s.issueTokenPair(ctx, createdUser.ID, client)
 	if err != nil {
-		return nil, errors.New("生成token失败: " + err.Error())
+		return nil, err
 	}
 
-	return tokenPair, nil
+	return &types.RegisterResp{TokenPair: tokenPair}, nil
 }
 
-// Login 用户登录
-func (s *Service) Login(ctx context.Context, req types.LoginReq) (*jwt.TokenPair, error) {
+// Login 用户登录，开启两步验证的用户返回挑战 token
+func (s *Service) Login(ctx context.Context, req types.LoginReq, client types.ClientInfo) (*types.LoginResp, error) {
 	// 验证用户凭据
-	loginUser, err := s.userSvc.ValidateLogin(ctx, req.Username, req.Password)
+	loginUser, err := s.userSvc.ValidateLogin(ctx, req.Username, req.Password, client.IP)
+	if err != nil {
+		return nil, err
+	}
+
+	// 需要两步验证
+	if loginUser.TwoFactorEnabled {
+		challengeToken, err := jwt.GenerateChallengeToken(loginUser.ID)
+		if err != nil {
+			return nil, errors.New("生成token失败: " + err.Error())
+		}
+		return &types.LoginResp{TwoFactorRequired: true, ChallengeToken: challengeToken}, nil
+	}
+
+	tokenPair, err := s.issueTokenPair(ctx, loginUser.ID, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.LoginResp{TokenPair: tokenPair}, nil
+}
+
+// LoginTwoFactor 两步验证登录，使用挑战 token 和 TOTP 验证码或恢复码换取 token 对
+func (s *Service) LoginTwoFactor(ctx context.Context, req types.LoginTwoFactorReq, client types.ClientInfo) (*jwt.TokenPair, error) {
+	claims, err := jwt.ParseToken(req.ChallengeToken)
+	if err != nil || claims.Type != jwt.ChallengeTokenType {
+		return nil, shared.ErrInvalidToken
+	}
+
+	// 挑战 token 仅可使用一次
+	revoked, err := jwt.IsRevoked(ctx, claims)
 	if err != nil {
 		return nil, err
 	}
+	if revoked {
+		return nil, shared.ErrInvalidToken
+	}
 
-	// 生成token对
-	tokenPair, err := jwt.GenerateTokenPair(loginUser.ID)
+	loginUser, err := s.userSvc.ValidateTwoFactorLogin(ctx, claims.UserID, req.Code, client.IP)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = jwt.RevokeToken(ctx, claims); err != nil {
+		return nil, err
+	}
+
+	return s.issueTokenPair(ctx, loginUser.ID, client)
+}
+
+// issueTokenPair 生成 token 对并记录登录会话
+func (s *Service) issueTokenPair(ctx context.Context, userID uint, client types.ClientInfo) (*jwt.TokenPair, error) {
+	tokenPair, err := jwt.GenerateTokenPair(ctx, userID)
 	if err != nil {
 		return nil, errors.New("生成token失败: " + err.Error())
 	}
 
+	if err = s.userSvc.CreateSession(ctx, userID, tokenPair.FamilyID, client.UserAgent, client.IP); err != nil {
+		return nil, err
+	}
 	return tokenPair, nil
 }
 
 // RefreshToken 刷新token
-func (s *Service) RefreshToken(_ context.Context, refreshToken string) (*jwt.TokenPair, error) {
-	tokenPair, err := jwt.RefreshToken(refreshToken)
+func (s *Service) RefreshToken(ctx context.Context, refreshToken string, client types.ClientInfo) (*jwt.TokenPair, error) {
+	tokenPair, err := jwt.RefreshToken(ctx, refreshToken)
 	if err != nil {
+		// 重复使用已轮换的 refresh token，整个家族已被吊销
+		if errors.Is(err, jwt.ErrRefreshTokenReused) {
+			logger.Warn(ctx, "refresh token reuse detected, token family revoked")
+			return nil, shared.ErrTokenReused
+		}
+		if errors.Is(err, jwt.ErrTokenInvalid) {
+			return nil, shared.TokenError(err)
+		}
 		return nil, errors.New("刷新token失败: " + err.Error())
 	}
 
+	if err = s.userSvc.TouchSession(ctx, tokenPair.FamilyID, client.IP); err != nil {
+		logger.Errw(ctx, err)
+	}
 	return tokenPair, nil
 }
+
+// Logout 登出，吊销当前 access token 及其所属的会话
+func (s *Service) Logout(ctx context.Context, claims *jwt.Claims) error {
+	if err := jwt.RevokeToken(ctx, claims); err != nil {
+		return err
+	}
+
+	if claims.FamilyID != "" {
+		return s.userSvc.RevokeSessionByFamily(ctx, claims.FamilyID)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-tpl/infra"
+	"go-tpl/infra/jwt"
+	"go-tpl/infra/logger"
+	"go-tpl/infra/mail"
+	"go-tpl/logic/shared"
+	"go-tpl/logic/user"
+	"go-tpl/web/types"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	verifyEmailCooldown    = time.Minute
+	verifyEmailMaxPerHour  = 5
+	verifyEmailCountWindow = time.Hour
+)
+
+// VerifyEmail 使用验证 token 激活账户
+func (s *Service) VerifyEmail(ctx context.Context, req types.VerifyEmailReq) error {
+	claims, err := jwt.ParseToken(req.Token)
+	if err != nil || claims.Type != jwt.EmailTokenType {
+		return shared.ErrVerifyTokenInvalid
+	}
+
+	err = s.userSvc.VerifyEmail(ctx, claims.UserID, claims.Email)
+	if errors.Is(err, shared.ErrRecordNotFound) {
+		return shared.ErrVerifyTokenInvalid
+	}
+	return err
+}
+
+// ResendVerification 重新发送验证邮件，邮箱不存在或无需验证时同样返回成功，避免泄露账户信息；
+// 发送频率按邮箱限制且在查询账户之前检查，限流结果与账户是否存在无关
+func (s *Service) ResendVerification(ctx context.Context, req types.ResendVerificationReq) error {
+	if err := s.limitVerificationEmail(ctx, req.Email); err != nil {
+		return err
+	}
+
+	u, err := s.userSvc.GetByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, shared.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if u.Status != shared.StatusPending {
+		return nil
+	}
+
+	if err = s.sendVerificationEmail(ctx, u, u.Email); err != nil {
+		// 不向调用方暴露发送结果
+		logger.Errw(ctx, err, logger.Int("user_id", int(u.ID)))
+	}
+	return nil
+}
+
+// UpdateProfile 更新个人资料，修改邮箱时向新邮箱发送验证链接，验证通过后新邮箱才生效
+func (s *Service) UpdateProfile(ctx context.Context, id uint, req types.UpdateProfileReq) (*types.UpdateProfileResp, error) {
+	current, err := s.userSvc.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// 仅在确实修改邮箱时发送验证邮件，计入发送频率限制
+	if req.Email != "" && req.Email != current.Email {
+		if err = s.limitVerificationEmail(ctx, req.Email); err != nil {
+			return nil, err
+		}
+	}
+
+	u, err := s.userSvc.UpdateProfile(ctx, id, req)
+	if err != nil {
+		return nil, err
+	}
+	if u.PendingEmail == "" {
+		return &types.UpdateProfileResp{}, nil
+	}
+
+	if err = s.sendVerificationEmail(ctx, u, u.PendingEmail); err != nil {
+		return nil, err
+	}
+	return &types.UpdateProfileResp{VerificationRequired: true}, nil
+}
+
+// limitVerificationEmail 限制验证邮件的发送频率，按邮箱哈希计数，与账户是否存在无关
+func (s *Service) limitVerificationEmail(ctx context.Context, email string) error {
+	emailHash := shared.HashToken(strings.ToLower(strings.TrimSpace(email)))
+
+	// 发送间隔限制
+	ok, err := s.redis.SetNX(ctx, fmt.Sprintf(shared.CacheEmailVerifyCooldown, emailHash), 1, verifyEmailCooldown).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return shared.ErrTooManyRequests
+	}
+
+	// 每小时发送次数限制
+	count, err := shared.IncrWithTTL(ctx, s.redis, fmt.Sprintf(shared.CacheEmailVerifyCount, emailHash), verifyEmailCountWindow)
+	if err != nil {
+		return err
+	}
+	if count > verifyEmailMaxPerHour {
+		return shared.ErrTooManyRequests
+	}
+	return nil
+}
+
+// sendVerificationEmail 向 email 发送邮箱验证链接，email 为用户当前邮箱或待验证的新邮箱
+func (s *Service) sendVerificationEmail(ctx context.Context, u *user.User, email string) error {
+	token, err := jwt.GenerateEmailToken(u.ID, email)
+	if err != nil {
+		return err
+	}
+
+	link := infra.Cfg.Server.PublicURL + "/verify-email?token=" + url.QueryEscape(token)
+	return s.mailer.Send(ctx, mail.Message{
+		To:      []string{email},
+		Subject: "验证邮箱",
+		Body: fmt.Sprintf("%s，您好：\n\n请在 24 小时内点击以下链接完成邮箱验证：\n%s\n\n如果不是您本人注册，请忽略此邮件。",
+			u.Username, link),
+	})
+}
@@ -0,0 +1,24 @@
+package dept
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Department 部门，按 ParentID 组织为树
+type Department struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	ParentID  uint           `gorm:"index;default:0" json:"parent_id"` // 0 表示顶级部门
+	Name      string         `gorm:"size:50;not null" json:"name"`
+	Sort      int            `gorm:"default:0" json:"sort"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	Children []*Department `gorm:"-" json:"children,omitempty"`
+}
+
+func (Department) TableName() string {
+	return "departments"
+}
@@ -0,0 +1,170 @@
+package dept
+
+import (
+	"context"
+	"errors"
+	"go-tpl/logic/shared"
+	"go-tpl/web/types"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+type Service struct {
+	db *gorm.DB
+}
+
+func NewService(db *gorm.DB) *Service {
+	return &Service{
+		db: db,
+	}
+}
+
+// Tree 获取部门树
+func (s *Service) Tree(ctx context.Context) ([]*Department, error) {
+	var list []*Department
+	if err := s.db.WithContext(ctx).Order("sort, id").Find(&list).Error; err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[uint]*Department, len(list))
+	for _, d := range list {
+		nodes[d.ID] = d
+	}
+
+	roots := make([]*Department, 0)
+	for _, d := range list {
+		if parent, ok := nodes[d.ParentID]; ok {
+			parent.Children = append(parent.Children, d)
+		} else {
+			roots = append(roots, d)
+		}
+	}
+	return roots, nil
+}
+
+// Get 获取单个部门
+func (s *Service) Get(ctx context.Context, id uint) (*Department, error) {
+	var dept Department
+	if err := s.db.WithContext(ctx).First(&dept, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, shared.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &dept, nil
+}
+
+// Create 创建部门
+func (s *Service) Create(ctx context.Context, req types.CreateDeptReq) (*Department, error) {
+	if req.ParentID != 0 {
+		if _, err := s.Get(ctx, req.ParentID); err != nil {
+			return nil, err
+		}
+	}
+
+	dept := Department{
+		ParentID: req.ParentID,
+		Name:     req.Name,
+		Sort:     req.Sort,
+	}
+	if err := s.db.WithContext(ctx).Create(&dept).Error; err != nil {
+		return nil, err
+	}
+	return &dept, nil
+}
+
+// Update 更新部门，上级部门不能是自身或自身的下级部门
+func (s *Service) Update(ctx context.Context, id uint, req types.UpdateDeptReq) error {
+	if _, err := s.Get(ctx, id); err != nil {
+		return err
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.Sort != nil {
+		updates["sort"] = *req.Sort
+	}
+	if req.ParentID != nil {
+		if *req.ParentID != 0 {
+			if _, err := s.Get(ctx, *req.ParentID); err != nil {
+				return err
+			}
+			descendants, err := s.Descendants(ctx, []uint{id})
+			if err != nil {
+				return err
+			}
+			if slices.Contains(descendants, *req.ParentID) {
+				return shared.ErrDeptCycle
+			}
+		}
+		updates["parent_id"] = *req.ParentID
+	}
+
+	if len(updates) == 0 {
+		return shared.ErrInvalidParam
+	}
+
+	return s.db.WithContext(ctx).Model(&Department{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// Delete 删除部门，存在下级部门或成员时不允许删除
+func (s *Service) Delete(ctx context.Context, id uint) error {
+	if _, err := s.Get(ctx, id); err != nil {
+		return err
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&Department{}).Where("parent_id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return shared.ErrDeptNotEmpty
+	}
+	if err := s.db.WithContext(ctx).Table("users").Where("dept_id = ? AND deleted_at IS NULL", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return shared.ErrDeptNotEmpty
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 删除角色自定义数据范围中的该部门
+		if err := tx.Exec("DELETE FROM role_depts WHERE dept_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Department{}, id).Error
+	})
+}
+
+// Descendants 返回部门及其全部下级部门
+func (s *Service) Descendants(ctx context.Context, ids []uint) ([]uint, error) {
+	var list []Department
+	if err := s.db.WithContext(ctx).Select("id", "parent_id").Find(&list).Error; err != nil {
+		return nil, err
+	}
+
+	children := make(map[uint][]uint)
+	for _, d := range list {
+		children[d.ParentID] = append(children[d.ParentID], d.ID)
+	}
+
+	var (
+		result  []uint
+		visited = make(map[uint]bool)
+		queue   = slices.Clone(ids)
+	)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if id == 0 || visited[id] {
+			continue
+		}
+		visited[id] = true
+		result = append(result, id)
+		queue = append(queue, children[id]...)
+	}
+	return result, nil
+}
@@ -0,0 +1,109 @@
+package permission
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-tpl/infra/logger"
+	"go-tpl/logic/shared"
+	"strconv"
+	"time"
+)
+
+// userPermissionTTL 用户权限缓存最长有效期，变更时会主动失效，过期时间仅作兜底
+const userPermissionTTL = 10 * time.Minute
+
+// cacheVersion 权限缓存版本，全局版本或用户版本与当前不一致时缓存视为失效
+type cacheVersion struct {
+	Global int64 `json:"v"`
+	User   int64 `json:"uv"`
+}
+
+// cachedPermissions 缓存的用户权限，记录计算前读取的缓存版本
+type cachedPermissions struct {
+	cacheVersion
+	Grants []Grant `json:"grants"`
+}
+
+// cachedConditions 缓存的权限自身访问条件，记录计算前读取的全局缓存版本
+type cachedConditions struct {
+	Global     int64             `json:"v"`
+	Conditions map[string]string `json:"conditions"`
+}
+
+// getCachedPermissions 读取用户权限缓存，同时返回当前缓存版本供回写使用；
+// 版本须在计算权限之前读取，计算期间发生的变更会使回写的结果失效
+func (s *Service) getCachedPermissions(ctx context.Context, userId uint) ([]Grant, cacheVersion, bool) {
+	var version cacheVersion
+	values, err := s.redis.MGet(ctx,
+		shared.CachePermissionVersion,
+		fmt.Sprintf(shared.CachePermissionUserVersion, userId),
+		fmt.Sprintf(shared.CachePermissionUser, userId)).Result()
+	if err != nil {
+		logger.Warn(ctx, "get permission cache failed", logger.Err(err))
+		return nil, version, false
+	}
+
+	if v, ok := values[0].(string); ok {
+		version.Global, _ = strconv.ParseInt(v, 10, 64)
+	}
+	if v, ok := values[1].(string); ok {
+		version.User, _ = strconv.ParseInt(v, 10, 64)
+	}
+	raw, ok := values[2].(string)
+	if !ok {
+		return nil, version, false
+	}
+
+	var cached cachedPermissions
+	if err = json.Unmarshal([]byte(raw), &cached); err != nil || cached.cacheVersion != version {
+		return nil, version, false
+	}
+	return cached.Grants, version, true
+}
+
+// getCachedConditions 读取权限自身访问条件的缓存，同时返回当前全局缓存版本供回写使用
+func (s *Service) getCachedConditions(ctx context.Context) (map[string]string, int64, bool) {
+	var version int64
+	values, err := s.redis.MGet(ctx, shared.CachePermissionVersion, shared.CachePermissionConditions).Result()
+	if err != nil {
+		logger.Warn(ctx, "get permission conditions cache failed", logger.Err(err))
+		return nil, version, false
+	}
+
+	if v, ok := values[0].(string); ok {
+		version, _ = strconv.ParseInt(v, 10, 64)
+	}
+	raw, ok := values[1].(string)
+	if !ok {
+		return nil, version, false
+	}
+
+	var cached cachedConditions
+	if err = json.Unmarshal([]byte(raw), &cached); err != nil || cached.Global != version {
+		return nil, version, false
+	}
+	return cached.Conditions, version, true
+}
+
+// setCachedConditions 写入权限自身访问条件的缓存，失败不影响本次结果
+func (s *Service) setCachedConditions(ctx context.Context, version int64, conditions map[string]string) {
+	data, err := json.Marshal(cachedConditions{Global: version, Conditions: conditions})
+	if err == nil {
+		err = s.redis.Set(ctx, shared.CachePermissionConditions, data, userPermissionTTL).Err()
+	}
+	if err != nil {
+		logger.Warn(ctx, "set permission conditions cache failed", logger.Err(err))
+	}
+}
+
+// setCachedPermissions 写入用户权限缓存，失败不影响本次结果
+func (s *Service) setCachedPermissions(ctx context.Context, userId uint, version cacheVersion, grants []Grant, ttl time.Duration) {
+	data, err := json.Marshal(cachedPermissions{cacheVersion: version, Grants: grants})
+	if err == nil {
+		err = s.redis.Set(ctx, fmt.Sprintf(shared.CachePermissionUser, userId), data, ttl).Err()
+	}
+	if err != nil {
+		logger.Warn(ctx, "set permission cache failed", logger.Err(err))
+	}
+}
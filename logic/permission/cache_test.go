@@ -0,0 +1,92 @@
+package permission
+
+import (
+	"context"
+	"go-tpl/logic/shared"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPermissionCache(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	s := &Service{redis: rdb}
+
+	grants := []Grant{{Code: "user:read"}, {Code: "user:update", Conditions: []string{officeCondition}}}
+
+	// cache 读取版本后回写，cached 返回缓存是否有效
+	cache := func(t *testing.T, userId uint) {
+		_, version, ok := s.getCachedPermissions(ctx, userId)
+		require.False(t, ok)
+		s.setCachedPermissions(ctx, userId, version, grants, userPermissionTTL)
+	}
+	cached := func(t *testing.T, userId uint) bool {
+		got, _, ok := s.getCachedPermissions(ctx, userId)
+		if ok {
+			assert.Equal(t, grants, got)
+		}
+		return ok
+	}
+
+	t.Run("Hit", func(t *testing.T) {
+		mr.FlushAll()
+		cache(t, 1)
+		assert.True(t, cached(t, 1))
+		assert.False(t, cached(t, 2))
+	})
+
+	t.Run("GlobalVersion", func(t *testing.T) {
+		mr.FlushAll()
+		cache(t, 1)
+		cache(t, 2)
+		require.NoError(t, shared.InvalidateAllPermissions(ctx, rdb))
+		assert.False(t, cached(t, 1))
+		assert.False(t, cached(t, 2))
+	})
+
+	t.Run("UserVersion", func(t *testing.T) {
+		mr.FlushAll()
+		cache(t, 1)
+		cache(t, 2)
+		require.NoError(t, shared.InvalidateUserPermissions(ctx, rdb, 1))
+		assert.False(t, cached(t, 1))
+		assert.True(t, cached(t, 2))
+	})
+
+	t.Run("StaleWrite", func(t *testing.T) {
+		mr.FlushAll()
+		// 读取版本后、回写之前发生变更，回写的结果不被使用
+		require.NoError(t, shared.InvalidateUserPermissions(ctx, rdb, 3))
+		_, version, ok := s.getCachedPermissions(ctx, 3)
+		require.False(t, ok)
+		require.NoError(t, shared.InvalidateUserPermissions(ctx, rdb, 3))
+		s.setCachedPermissions(ctx, 3, version, grants, userPermissionTTL)
+		assert.False(t, cached(t, 3))
+
+		_, version, _ = s.getCachedPermissions(ctx, 4)
+		require.NoError(t, shared.InvalidateAllPermissions(ctx, rdb))
+		s.setCachedPermissions(ctx, 4, version, grants, userPermissionTTL)
+		assert.False(t, cached(t, 4))
+	})
+
+	t.Run("Conditions", func(t *testing.T) {
+		mr.FlushAll()
+		conditions := map[string]string{"user:update": officeCondition}
+		_, version, ok := s.getCachedConditions(ctx)
+		require.False(t, ok)
+		s.setCachedConditions(ctx, version, conditions)
+
+		got, _, ok := s.getCachedConditions(ctx)
+		require.True(t, ok)
+		assert.Equal(t, conditions, got)
+
+		require.NoError(t, shared.InvalidateAllPermissions(ctx, rdb))
+		_, _, ok = s.getCachedConditions(ctx)
+		assert.False(t, ok)
+	})
+}
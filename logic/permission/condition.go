@@ -0,0 +1,274 @@
+package permission
+
+import (
+	"context"
+	"encoding/json"
+	"go-tpl/infra/expr"
+	"go-tpl/infra/logger"
+	"go-tpl/logic/shared"
+	"go-tpl/logic/user"
+	"go-tpl/web/types"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Grant 用户的一项有效权限，Conditions 为须全部满足的访问条件（权限自身条件与角色权限关联条件），为空表示无条件
+type Grant struct {
+	Code       string   `json:"code"`
+	Conditions []string `json:"conditions,omitempty"`
+}
+
+// grantRow 经由某个角色获得权限的记录
+type grantRow struct {
+	Code                string
+	PermissionCondition string
+	LinkCondition       string
+}
+
+// mergeGrants 合并同一权限的多次授予：存在无条件授予时忽略其余附带条件的授予，相同条件只保留一份
+func mergeGrants(rows []grantRow) []Grant {
+	unconditional := make(map[string]bool)
+	for _, row := range rows {
+		if row.PermissionCondition == "" && row.LinkCondition == "" {
+			unconditional[row.Code] = true
+		}
+	}
+
+	seen := make(map[string]bool)
+	grants := make([]Grant, 0, len(rows))
+	for _, row := range rows {
+		grant := Grant{Code: row.Code}
+		if !unconditional[row.Code] {
+			for _, condition := range []string{row.PermissionCondition, row.LinkCondition} {
+				if condition != "" {
+					grant.Conditions = append(grant.Conditions, condition)
+				}
+			}
+		}
+
+		key := strings.Join(append([]string{grant.Code}, grant.Conditions...), "\n")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		grants = append(grants, grant)
+	}
+
+	slices.SortStableFunc(grants, func(a, b Grant) int {
+		return strings.Compare(a.Code, b.Code)
+	})
+	return grants
+}
+
+// ==================== 请求属性 ====================
+
+// Attributes 权限校验时的请求属性，供访问条件求值
+type Attributes struct {
+	IP       string
+	Time     time.Time
+	Resource func(ctx context.Context) (map[string]any, error) // 按需加载请求的目标资源，为空表示没有目标资源
+}
+
+type attributesKey struct{}
+
+// WithAttributes 在上下文中记录请求属性，由权限校验中间件设置
+func WithAttributes(ctx context.Context, attrs Attributes) context.Context {
+	return context.WithValue(ctx, attributesKey{}, attrs)
+}
+
+// attributesFromContext 获取请求属性，内部调用（如模拟登录校验）没有请求属性时仅提供当前时间
+func attributesFromContext(ctx context.Context) Attributes {
+	attrs, _ := ctx.Value(attributesKey{}).(Attributes)
+	if attrs.Time.IsZero() {
+		attrs.Time = time.Now()
+	}
+	return attrs
+}
+
+// ToAttributes 将记录按 JSON 字段转换为条件表达式可访问的对象
+func ToAttributes(v any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var attrs map[string]any
+	if err = json.Unmarshal(data, &attrs); err != nil {
+		return nil, err
+	}
+	return attrs, nil
+}
+
+// timeAttributes 时间属性，weekday 中 0 表示周日
+func timeAttributes(t time.Time) map[string]any {
+	return map[string]any{
+		"hour":    t.Hour(),
+		"minute":  t.Minute(),
+		"weekday": int(t.Weekday()),
+		"date":    t.Format(time.DateOnly),
+		"unix":    t.Unix(),
+	}
+}
+
+// ==================== 条件求值 ====================
+
+// programs 已编译的访问条件，条件由管理员配置，数量有限
+var programs sync.Map
+
+// compileCached 编译访问条件并按源码缓存
+func compileCached(src string) (*expr.Program, error) {
+	if program, ok := programs.Load(src); ok {
+		return program.(*expr.Program), nil
+	}
+
+	program, err := shared.CompileCondition(src)
+	if err != nil {
+		return nil, err
+	}
+	programs.Store(src, program)
+	return program, nil
+}
+
+// conditionEnv 单次权限校验的求值环境，属性在条件引用时才加载，同一次校验内只加载一次
+type conditionEnv struct {
+	svc    *Service
+	userId uint
+	attrs  Attributes
+	env    expr.Env
+}
+
+func (s *Service) newConditionEnv(userId uint, attrs Attributes) *conditionEnv {
+	return &conditionEnv{
+		svc:    s,
+		userId: userId,
+		attrs:  attrs,
+		env:    make(expr.Env),
+	}
+}
+
+// permits 判断 grants 是否允许访问 code：required 为 code 自身的访问条件，无论经由哪项授予匹配都须满足；
+// 此外任一匹配的授予的条件全部满足即通过
+func (e *conditionEnv) permits(ctx context.Context, grants []Grant, code, required string) bool {
+	matched := make([]Grant, 0)
+	for _, grant := range grants {
+		if Match(grant.Code, code) {
+			matched = append(matched, grant)
+		}
+	}
+	if len(matched) == 0 {
+		return false
+	}
+	if required != "" && !e.satisfies(ctx, []string{required}) {
+		return false
+	}
+
+	for _, grant := range matched {
+		if len(grant.Conditions) == 0 || e.satisfies(ctx, grant.Conditions) {
+			return true
+		}
+	}
+	return false
+}
+
+// satisfies 判断条件是否全部满足，条件无法编译、属性加载失败或求值出错时视为不满足
+func (e *conditionEnv) satisfies(ctx context.Context, conditions []string) bool {
+	for _, condition := range conditions {
+		ok, err := e.eval(ctx, condition)
+		if err != nil {
+			logger.Warn(ctx, "evaluate permission condition failed",
+				logger.Str("condition", condition),
+				logger.Err(err))
+			return false
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *conditionEnv) eval(ctx context.Context, condition string) (bool, error) {
+	program, err := compileCached(condition)
+	if err != nil {
+		return false, err
+	}
+	if err = e.load(ctx, program.Vars()); err != nil {
+		return false, err
+	}
+	return program.EvalBool(e.env)
+}
+
+// load 加载表达式引用的属性：ip、time、user（当前用户）、resource（目标资源），其他变量为 null
+func (e *conditionEnv) load(ctx context.Context, vars []string) error {
+	for _, name := range vars {
+		if _, loaded := e.env[name]; loaded {
+			continue
+		}
+
+		var value any
+		switch name {
+		case "ip":
+			value = e.attrs.IP
+		case "time":
+			value = timeAttributes(e.attrs.Time)
+		case "user":
+			attrs, err := e.svc.userAttributes(ctx, e.userId)
+			if err != nil {
+				return err
+			}
+			value = attrs
+		case "resource":
+			if e.attrs.Resource != nil {
+				attrs, err := e.attrs.Resource(ctx)
+				if err != nil {
+					return err
+				}
+				value = attrs
+			}
+		}
+		e.env[name] = value
+	}
+	return nil
+}
+
+// userAttributes 当前用户的属性，不受数据范围限制
+func (s *Service) userAttributes(ctx context.Context, userId uint) (map[string]any, error) {
+	var u user.User
+	if err := s.db.WithContext(ctx).First(&u, userId).Error; err != nil {
+		return nil, err
+	}
+	return ToAttributes(u)
+}
+
+// ==================== 条件测试 ====================
+
+// ConditionTestResult 访问条件测试结果
+type ConditionTestResult struct {
+	Result bool     `json:"result"`
+	Vars   []string `json:"vars"` // 条件引用的属性
+}
+
+// TestCondition 使用示例属性对访问条件求值，用于配置条件前验证表达式
+func (s *Service) TestCondition(ctx context.Context, req types.TestConditionReq) (*ConditionTestResult, error) {
+	program, err := shared.CompileCondition(req.Condition)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if req.Time != nil {
+		now = req.Time.Local()
+	}
+	ok, err := program.EvalBool(expr.Env{
+		"ip":       req.IP,
+		"time":     timeAttributes(now),
+		"user":     req.User,
+		"resource": req.Resource,
+	})
+	if err != nil {
+		return nil, shared.ErrConditionInvalid.WithDetail(err.Error())
+	}
+	return &ConditionTestResult{Result: ok, Vars: program.Vars()}, nil
+}
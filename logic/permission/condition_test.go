@@ -0,0 +1,125 @@
+package permission
+
+import (
+	"context"
+	"fmt"
+	"go-tpl/logic/shared"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	officeCondition    = "cidr(ip, '10.0.0.0/8')"
+	workHoursCondition = "time.hour >= 9 && time.hour < 18"
+)
+
+func TestMergeGrants(t *testing.T) {
+	cases := []struct {
+		name string
+		rows []grantRow
+		want []Grant
+	}{
+		{"empty", nil, []Grant{}},
+		{"sorted by code", []grantRow{{Code: "user:read"}, {Code: "role:read"}}, []Grant{{Code: "role:read"}, {Code: "user:read"}}},
+		{"duplicate unconditional", []grantRow{{Code: "user:read"}, {Code: "user:read"}}, []Grant{{Code: "user:read"}}},
+		{
+			"permission and link condition",
+			[]grantRow{{Code: "user:update", PermissionCondition: officeCondition, LinkCondition: workHoursCondition}},
+			[]Grant{{Code: "user:update", Conditions: []string{officeCondition, workHoursCondition}}},
+		},
+		{
+			"unconditional overrides conditional",
+			[]grantRow{{Code: "user:update", LinkCondition: workHoursCondition}, {Code: "user:update"}},
+			[]Grant{{Code: "user:update"}},
+		},
+		{
+			"different link conditions kept",
+			[]grantRow{{Code: "user:update", LinkCondition: officeCondition}, {Code: "user:update", LinkCondition: workHoursCondition}},
+			[]Grant{{Code: "user:update", Conditions: []string{officeCondition}}, {Code: "user:update", Conditions: []string{workHoursCondition}}},
+		},
+		{
+			"same condition merged",
+			[]grantRow{{Code: "user:update", PermissionCondition: officeCondition}, {Code: "user:update", PermissionCondition: officeCondition}},
+			[]Grant{{Code: "user:update", Conditions: []string{officeCondition}}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, mergeGrants(c.rows))
+		})
+	}
+}
+
+func TestPermits(t *testing.T) {
+	ctx := context.Background()
+	workTime := time.Date(2026, 10, 19, 10, 0, 0, 0, time.Local)
+	night := time.Date(2026, 10, 19, 23, 0, 0, 0, time.Local)
+
+	cases := []struct {
+		name     string
+		grants   []Grant
+		required string
+		ip       string
+		at       time.Time
+		want     bool
+	}{
+		{"no grants", nil, "", "10.0.0.1", workTime, false},
+		{"no matching grant", []Grant{{Code: "role:update"}}, "", "10.0.0.1", workTime, false},
+		{"unconditional grant", []Grant{{Code: "user:update"}}, "", "192.168.0.1", night, true},
+
+		// 授予的条件
+		{"grant condition satisfied", []Grant{{Code: "user:update", Conditions: []string{officeCondition}}}, "", "10.0.0.1", night, true},
+		{"grant condition unsatisfied", []Grant{{Code: "user:update", Conditions: []string{officeCondition}}}, "", "192.168.0.1", night, false},
+		{"all grant conditions required", []Grant{{Code: "user:update", Conditions: []string{officeCondition, workHoursCondition}}}, "", "10.0.0.1", night, false},
+		{
+			"any grant satisfied",
+			[]Grant{{Code: "user:update", Conditions: []string{officeCondition}}, {Code: "user:*", Conditions: []string{workHoursCondition}}},
+			"", "192.168.0.1", workTime, true,
+		},
+
+		// 权限自身的条件不因通配符或无条件授予而跳过
+		{"super wildcard checks required", []Grant{{Code: "*"}}, officeCondition, "192.168.0.1", workTime, false},
+		{"module wildcard checks required", []Grant{{Code: "user:*"}}, officeCondition, "192.168.0.1", workTime, false},
+		{"wildcard with required satisfied", []Grant{{Code: "*"}}, officeCondition, "10.0.0.1", night, true},
+		{
+			"required and grant condition",
+			[]Grant{{Code: "user:*", Conditions: []string{workHoursCondition}}},
+			officeCondition, "10.0.0.1", night, false,
+		},
+		{
+			"required with unconditional grant",
+			[]Grant{{Code: "user:update", Conditions: []string{workHoursCondition}}, {Code: "*"}},
+			officeCondition, "10.0.0.1", night, true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			env := (&Service{}).newConditionEnv(1, Attributes{IP: c.ip, Time: c.at})
+			assert.Equal(t, c.want, env.permits(ctx, c.grants, "user:update", c.required))
+		})
+	}
+}
+
+func TestHasPermissionWildcardCondition(t *testing.T) {
+	mr := miniredis.RunT(t)
+	s := &Service{redis: redis.NewClient(&redis.Options{Addr: mr.Addr()})}
+
+	// 缓存版本均为 0，权限和条件直接取自缓存
+	require.NoError(t, mr.Set(fmt.Sprintf(shared.CachePermissionUser, 1), `{"v":0,"uv":0,"grants":[{"code":"*"}]}`))
+	require.NoError(t, mr.Set(shared.CachePermissionConditions, `{"v":0,"conditions":{"user:update":"cidr(ip, '10.0.0.0/8')"}}`))
+
+	check := func(ip, code string) bool {
+		ctx := WithAttributes(context.Background(), Attributes{IP: ip})
+		ok, err := s.HasPermission(ctx, 1, code)
+		require.NoError(t, err)
+		return ok
+	}
+	assert.True(t, check("10.0.0.1", "user:update"))
+	assert.False(t, check("192.168.0.1", "user:update"))
+	assert.True(t, check("192.168.0.1", "user:read"))
+}
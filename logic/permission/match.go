@@ -0,0 +1,73 @@
+package permission
+
+import (
+	"go-tpl/logic/shared"
+	"regexp"
+	"strings"
+)
+
+const (
+	// Wildcard 通配段，单独作为权限代码时表示超级权限
+	Wildcard     = "*"
+	codeSep      = ":"
+	maxCodeLen   = 50
+	maxCodeDepth = 5
+)
+
+var codeSegment = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// ValidateCode 校验权限代码格式：以冒号分隔的若干段（如 user:create、user:session:revoke），
+// 每段为小写字母开头的标识符或通配符 *
+func ValidateCode(code string) error {
+	if code == "" || len(code) > maxCodeLen {
+		return shared.ErrPermissionCode
+	}
+
+	segments := strings.Split(code, codeSep)
+	if len(segments) > maxCodeDepth {
+		return shared.ErrPermissionCode
+	}
+	for _, seg := range segments {
+		if seg != Wildcard && !codeSegment.MatchString(seg) {
+			return shared.ErrPermissionCode.WithDetail(code)
+		}
+	}
+	return nil
+}
+
+// Match 判断已授予的权限代码是否覆盖所需权限
+// * 段匹配任意一段，位于末尾时匹配其后的全部段：user:* 覆盖 user:read 和 user:session:revoke，
+// *:read 覆盖 user:read，* 覆盖全部权限；所需权限中的 * 仅被 * 覆盖
+func Match(granted, required string) bool {
+	if granted == required {
+		return true
+	}
+
+	g := strings.Split(granted, codeSep)
+	r := strings.Split(required, codeSep)
+	for i, seg := range g {
+		if i >= len(r) {
+			return false
+		}
+		if seg == Wildcard {
+			if i == len(g)-1 {
+				return true
+			}
+			continue
+		}
+		if seg != r[i] {
+			return false
+		}
+	}
+	return len(g) == len(r)
+}
+
+// MatchAny 判断已授予的权限代码中是否有覆盖所需权限的
+func MatchAny(granted []string, required string) bool {
+	for _, code := range granted {
+		if Match(code, required) {
+			return true
+		}
+	}
+	return false
+}
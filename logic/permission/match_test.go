@@ -0,0 +1,99 @@
+package permission
+
+import (
+	"go-tpl/logic/shared"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		granted  string
+		required string
+		want     bool
+	}{
+		{"user:read", "user:read", true},
+		{"user:read", "user:create", false},
+		{"user:read", "role:read", false},
+
+		// 末尾的 * 匹配其后的全部段
+		{"user:*", "user:read", true},
+		{"user:*", "user:session:revoke", true},
+		{"user:*", "user", false},
+		{"user:*", "role:read", false},
+		{"user:session:*", "user:session:revoke", true},
+		{"user:session:*", "user:read", false},
+
+		// 中间的 * 只匹配一段
+		{"*:read", "user:read", true},
+		{"*:read", "role:read", true},
+		{"*:read", "user:create", false},
+		{"*:read", "user:session:read", false},
+		{"*:read", "user:read:self", false},
+		{"user:*:revoke", "user:session:revoke", true},
+		{"user:*:revoke", "user:session:list", false},
+
+		// 超级权限
+		{"*", "user:read", true},
+		{"*", "user:session:revoke", true},
+		{"*", "*", true},
+
+		// 所需权限中的 * 仅被 * 覆盖
+		{"user:read", "*", false},
+		{"user:*", "*", false},
+		{"*:read", "*", false},
+		{"*:read", "*:*", false},
+
+		// 前缀不构成覆盖
+		{"user", "user:read", false},
+		{"user:read", "user:read:self", false},
+		{"user:read:self", "user:read", false},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, Match(c.granted, c.required), "%s covers %s", c.granted, c.required)
+	}
+}
+
+func TestMatchAny(t *testing.T) {
+	assert.True(t, MatchAny([]string{"role:read", "user:*"}, "user:session:revoke"))
+	assert.False(t, MatchAny([]string{"role:read", "*:create"}, "user:read"))
+	assert.False(t, MatchAny(nil, "user:read"))
+}
+
+func TestValidateCode(t *testing.T) {
+	valid := []string{
+		"user",
+		"user:read",
+		"user:session:revoke",
+		"user_group:batch-import",
+		"user:*",
+		"*:read",
+		"*",
+		"a1:b2:c3:d4:e5",
+	}
+	for _, code := range valid {
+		assert.NoError(t, ValidateCode(code), code)
+	}
+
+	invalid := []string{
+		"",
+		"User:read",
+		"user:",
+		":read",
+		"user::read",
+		"user:1read",
+		"user:re ad",
+		"user:**",
+		"user:r*",
+		"a:b:c:d:e:f",
+		strings.Repeat("a", maxCodeLen+1),
+	}
+	for _, code := range invalid {
+		var e shared.Error
+		require.ErrorAs(t, ValidateCode(code), &e, code)
+		assert.Equal(t, shared.ErrPermissionCode.Code, e.Code, code)
+	}
+}
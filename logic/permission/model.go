@@ -6,16 +6,25 @@ import (
 	"gorm.io/gorm"
 )
 
+// Permission 权限，按 ParentID 组织为菜单、按钮、接口构成的树
 type Permission struct {
 	ID          uint           `gorm:"primaryKey" json:"id"`
+	ParentID    uint           `gorm:"index;default:0" json:"parent_id"` // 0 表示顶级权限
 	Code        string         `gorm:"uniqueIndex;size:50;not null" json:"code"`
 	Name        string         `gorm:"size:100;not null" json:"name"`
 	Description string         `gorm:"size:255" json:"description"`
 	Module      string         `gorm:"size:50;index" json:"module"`
-	Status      int8           `gorm:"default:1" json:"status"` // 1-正常, 0-禁用
+	Type        string         `gorm:"size:10;default:api" json:"type"` // menu-菜单, button-按钮, api-接口
+	Path        string         `gorm:"size:255" json:"path"`            // 前端路由路径，菜单使用
+	Icon        string         `gorm:"size:100" json:"icon"`
+	Sort        int            `gorm:"default:0" json:"sort"`
+	Condition   string         `gorm:"column:condition_expr;size:1024" json:"condition"` // 访问条件表达式，为空表示无条件
+	Status      int8           `gorm:"default:1" json:"status"`                          // 1-正常, 0-禁用
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+
+	Children []*Permission `gorm:"-" json:"children,omitempty"`
 }
 
 func (Permission) TableName() string {
@@ -0,0 +1,124 @@
+package permission
+
+import (
+	"context"
+	"fmt"
+	"go-tpl/infra/logger"
+	"go-tpl/logic/shared"
+	"slices"
+	"strings"
+)
+
+// CodeAdminAccess 持有该权限的用户视为管理员，由业务代码而非路由校验
+const CodeAdminAccess = "admin:access"
+
+// builtinCodes 不由路由声明、在业务代码中校验的权限代码，不视为孤立权限
+var builtinCodes = []string{CodeAdminAccess}
+
+// Route 路由声明的权限代码
+type Route struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Code   string `json:"code"`
+	Module string `json:"module"`
+}
+
+// RouteMap 路由与权限代码的对应关系
+type RouteMap struct {
+	Routes  []Route  `json:"routes"`
+	Orphans []string `json:"orphans"` // 未被任何路由使用的权限代码
+}
+
+// SyncRoutes 启动时同步路由声明的权限代码：补充缺失的权限（模块取声明该代码的路由组），
+// 恢复被软删除的权限，并记录未被任何路由使用的权限
+func (s *Service) SyncRoutes(ctx context.Context, routes []Route) error {
+	// 按代码去重，同一代码在多个路由组中声明时优先取与代码首段同名的路由组作为模块
+	modules := make(map[string]string)
+	codes := make([]string, 0)
+	for _, route := range routes {
+		if err := ValidateCode(route.Code); err != nil {
+			return fmt.Errorf("route %s %s: %w", route.Method, route.Path, err)
+		}
+		if _, ok := modules[route.Code]; !ok {
+			codes = append(codes, route.Code)
+		} else if !strings.HasPrefix(route.Code, route.Module+codeSep) {
+			continue
+		}
+		modules[route.Code] = route.Module
+	}
+
+	if len(codes) > 0 {
+		var existing []Permission
+		if err := s.db.WithContext(ctx).Unscoped().Where("code IN ?", codes).Find(&existing).Error; err != nil {
+			return err
+		}
+		found := make(map[string]Permission, len(existing))
+		for _, p := range existing {
+			found[p.Code] = p
+		}
+
+		for _, code := range codes {
+			p, ok := found[code]
+			if !ok {
+				permission := Permission{
+					Code:   code,
+					Name:   code,
+					Module: modules[code],
+					Status: shared.StatusActive,
+				}
+				if err := s.db.WithContext(ctx).Create(&permission).Error; err != nil {
+					return err
+				}
+				logger.Info(ctx, "route permission registered", logger.Str("code", code), logger.Str("module", permission.Module))
+				continue
+			}
+
+			if p.DeletedAt.Valid {
+				if err := s.db.WithContext(ctx).Unscoped().Model(&Permission{}).Where("id = ?", p.ID).Update("deleted_at", nil).Error; err != nil {
+					return err
+				}
+				logger.Warn(ctx, "deleted route permission restored", logger.Str("code", code))
+			}
+		}
+	}
+
+	orphans, err := s.OrphanCodes(ctx, routes)
+	if err != nil {
+		return err
+	}
+	if len(orphans) > 0 {
+		logger.Warn(ctx, "permissions not used by any route", logger.Str("codes", strings.Join(orphans, ",")))
+	}
+	return nil
+}
+
+// OrphanCodes 获取未被任何路由使用的权限代码，通配符权限和业务代码校验的权限除外
+func (s *Service) OrphanCodes(ctx context.Context, routes []Route) ([]string, error) {
+	var codes []string
+	if err := s.db.WithContext(ctx).Model(&Permission{}).Order("code").Pluck("code", &codes).Error; err != nil {
+		return nil, err
+	}
+
+	used := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		used[route.Code] = true
+	}
+
+	orphans := make([]string, 0)
+	for _, code := range codes {
+		if used[code] || strings.Contains(code, Wildcard) || slices.Contains(builtinCodes, code) {
+			continue
+		}
+		orphans = append(orphans, code)
+	}
+	return orphans, nil
+}
+
+// GetRouteMap 获取路由与权限代码的对应关系
+func (s *Service) GetRouteMap(ctx context.Context, routes []Route) (*RouteMap, error) {
+	orphans, err := s.OrphanCodes(ctx, routes)
+	if err != nil {
+		return nil, err
+	}
+	return &RouteMap{Routes: routes, Orphans: orphans}, nil
+}
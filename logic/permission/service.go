@@ -5,18 +5,26 @@ import (
 	"errors"
 	"go-tpl/logic/role"
 	"go-tpl/logic/shared"
+	"go-tpl/logic/user"
 	"go-tpl/web/types"
+	"slices"
+	"time"
 
+	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 )
 
 type Service struct {
-	db *gorm.DB
+	db      *gorm.DB
+	redis   *redis.Client
+	roleSvc *role.Service
 }
 
-func NewService(db *gorm.DB) *Service {
+func NewService(db *gorm.DB, redis *redis.Client, roleSvc *role.Service) *Service {
 	return &Service{
-		db: db,
+		db:      db,
+		redis:   redis,
+		roleSvc: roleSvc,
 	}
 }
 
@@ -66,6 +74,10 @@ func (s *Service) Get(ctx context.Context, id uint) (*Permission, error) {
 
 // Create 创建权限
 func (s *Service) Create(ctx context.Context, req types.CreatePermissionReq) (*Permission, error) {
+	if err := ValidateCode(req.Code); err != nil {
+		return nil, err
+	}
+
 	// 检查权限代码是否已存在
 	var count int64
 	if err := s.db.WithContext(ctx).Model(&Permission{}).Where("code = ?", req.Code).Count(&count).Error; err != nil {
@@ -75,11 +87,31 @@ func (s *Service) Create(ctx context.Context, req types.CreatePermissionReq) (*P
 		return nil, shared.ErrPermissionExists
 	}
 
+	if req.ParentID != 0 {
+		if _, err := s.Get(ctx, req.ParentID); err != nil {
+			return nil, err
+		}
+	}
+	if req.Type == "" {
+		req.Type = shared.PermissionTypeAPI
+	}
+	if req.Condition != "" {
+		if _, err := shared.CompileCondition(req.Condition); err != nil {
+			return nil, err
+		}
+	}
+
 	permission := Permission{
+		ParentID:    req.ParentID,
 		Code:        req.Code,
 		Name:        req.Name,
 		Description: req.Description,
 		Module:      req.Module,
+		Type:        req.Type,
+		Path:        req.Path,
+		Icon:        req.Icon,
+		Sort:        req.Sort,
+		Condition:   req.Condition,
 		Status:      shared.StatusActive,
 	}
 
@@ -99,6 +131,10 @@ func (s *Service) Update(ctx context.Context, id uint, req types.UpdatePermissio
 
 	updates := make(map[string]interface{})
 	if req.Code != "" && req.Code != permission.Code {
+		if err = ValidateCode(req.Code); err != nil {
+			return err
+		}
+
 		// 检查权限代码是否已存在
 		var count int64
 		if err = s.db.WithContext(ctx).Model(&Permission{}).Where("code = ? AND id != ?", req.Code, id).Count(&count).Error; err != nil {
@@ -122,18 +158,53 @@ func (s *Service) Update(ctx context.Context, id uint, req types.UpdatePermissio
 		updates["module"] = *req.Module
 	}
 
+	if req.ParentID != nil {
+		if err = s.checkParent(ctx, id, *req.ParentID); err != nil {
+			return err
+		}
+		updates["parent_id"] = *req.ParentID
+	}
+
+	if req.Type != nil {
+		updates["type"] = *req.Type
+	}
+
+	if req.Path != nil {
+		updates["path"] = *req.Path
+	}
+
+	if req.Icon != nil {
+		updates["icon"] = *req.Icon
+	}
+
+	if req.Sort != nil {
+		updates["sort"] = *req.Sort
+	}
+
 	if req.Status != nil {
 		updates["status"] = *req.Status
 	}
 
+	if req.Condition != nil {
+		if *req.Condition != "" {
+			if _, err = shared.CompileCondition(*req.Condition); err != nil {
+				return err
+			}
+		}
+		updates["condition_expr"] = *req.Condition
+	}
+
 	if len(updates) == 0 {
 		return shared.ErrInvalidParam
 	}
 
-	return s.db.WithContext(ctx).Model(&Permission{}).Where("id = ?", id).Updates(updates).Error
+	if err = s.db.WithContext(ctx).Model(&Permission{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+		return err
+	}
+	return shared.InvalidateAllPermissions(ctx, s.redis)
 }
 
-// Delete 删除权限
+// Delete 删除权限，存在下级权限时不允许删除
 func (s *Service) Delete(ctx context.Context, id uint) error {
 	// 检查权限是否存在
 	_, err := s.Get(ctx, id)
@@ -141,8 +212,16 @@ func (s *Service) Delete(ctx context.Context, id uint) error {
 		return err
 	}
 
+	var count int64
+	if err = s.db.WithContext(ctx).Model(&Permission{}).Where("parent_id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return shared.ErrPermissionNotEmpty
+	}
+
 	// 开启事务，同时删除角色权限关联
-	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// 删除角色权限关联
 		if err = tx.Where("permission_id = ?", id).Delete(&role.RolePermission{}).Error; err != nil {
 			return err
@@ -151,6 +230,10 @@ func (s *Service) Delete(ctx context.Context, id uint) error {
 		// 删除权限
 		return tx.Delete(&Permission{}, id).Error
 	})
+	if err != nil {
+		return err
+	}
+	return shared.InvalidateAllPermissions(ctx, s.redis)
 }
 
 // UpdateStatus 更新权限状态
@@ -160,7 +243,10 @@ func (s *Service) UpdateStatus(ctx context.Context, id uint, status int8) error
 		return err
 	}
 
-	return s.db.WithContext(ctx).Model(&Permission{}).Where("id = ?", id).Update("status", status).Error
+	if err = s.db.WithContext(ctx).Model(&Permission{}).Where("id = ?", id).Update("status", status).Error; err != nil {
+		return err
+	}
+	return shared.InvalidateAllPermissions(ctx, s.redis)
 }
 
 // GetModules 获取所有模块列表
@@ -187,3 +273,172 @@ func (s *Service) GetPermissionRoles(ctx context.Context, permissionId uint) ([]
 	}
 	return roleIds, nil
 }
+
+// GetUserPermissions 获取用户有效权限代码，包含角色从祖先角色继承的权限（仅包含启用状态的角色和权限）；
+// 附带访问条件的权限同样列出，条件在 HasPermission 校验时求值
+func (s *Service) GetUserPermissions(ctx context.Context, userId uint) ([]string, error) {
+	grants, err := s.GetUserGrants(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, len(grants))
+	for _, grant := range grants {
+		codes = append(codes, grant.Code)
+	}
+	return slices.Compact(codes), nil
+}
+
+// GetUserGrants 获取用户有效权限及其访问条件，按权限代码排序，结果按用户缓存
+func (s *Service) GetUserGrants(ctx context.Context, userId uint) ([]Grant, error) {
+	grants, version, ok := s.getCachedPermissions(ctx, userId)
+	if ok {
+		return grants, nil
+	}
+
+	grants, ttl, err := s.loadUserGrants(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	s.setCachedPermissions(ctx, userId, version, grants, ttl)
+	return grants, nil
+}
+
+// loadUserGrants 从数据库计算用户有效权限，同时返回缓存有效期：
+// 不超过 userPermissionTTL，且不跨越角色授予下一次生效或过期的时间
+func (s *Service) loadUserGrants(ctx context.Context, userId uint) ([]Grant, time.Duration, error) {
+	var userRoles []user.UserRole
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userId).Find(&userRoles).Error; err != nil {
+		return nil, 0, err
+	}
+
+	now := time.Now()
+	ttl := userPermissionTTL
+	roleIds := make([]uint, 0, len(userRoles))
+	for _, ur := range userRoles {
+		if ur.ActiveAt(now) {
+			roleIds = append(roleIds, ur.RoleID)
+		}
+		for _, t := range []*time.Time{ur.StartsAt, ur.ExpiresAt} {
+			if t != nil && t.After(now) && t.Sub(now) < ttl {
+				ttl = t.Sub(now)
+			}
+		}
+	}
+
+	// 沿角色继承关系展开，禁用的角色不参与
+	roleIds, err := s.roleSvc.ExpandActive(ctx, roleIds)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(roleIds) == 0 {
+		return make([]Grant, 0), ttl, nil
+	}
+
+	var rows []grantRow
+	err = s.db.WithContext(ctx).Model(&Permission{}).
+		Select("permissions.code, permissions.condition_expr AS permission_condition, role_permissions.condition_expr AS link_condition").
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Where("role_permissions.role_id IN ? AND permissions.status = ?", roleIds, shared.StatusActive).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return mergeGrants(rows), ttl, nil
+}
+
+// HasGrantedCode 检查用户是否被授予覆盖指定权限的代码，不对访问条件求值，
+// 用于判断用户身份（如是否为管理员），避免结果随当前请求的属性变化
+func (s *Service) HasGrantedCode(ctx context.Context, userId uint, code string) (bool, error) {
+	grants, err := s.GetUserGrants(ctx, userId)
+	if err != nil {
+		return false, err
+	}
+	for _, grant := range grants {
+		if Match(grant.Code, code) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// UncoveredGrants 返回 userId 的有效权限中未被 actorId 的权限覆盖的权限代码，
+// 用于确认模拟登录等代为操作的场景不会使发起者获得超出自身的权限
+func (s *Service) UncoveredGrants(ctx context.Context, actorId, userId uint) ([]string, error) {
+	actorGrants, err := s.GetUserGrants(ctx, actorId)
+	if err != nil {
+		return nil, err
+	}
+	userGrants, err := s.GetUserGrants(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	return uncoveredGrants(actorGrants, userGrants), nil
+}
+
+// uncoveredGrants 返回 target 中未被 holder 覆盖的权限代码。
+// 覆盖要求 holder 的权限代码匹配该权限，且其访问条件都是目标授予的条件之一（条件越少范围越大）
+func uncoveredGrants(holder, target []Grant) []string {
+	var uncovered []string
+	for _, t := range target {
+		covered := slices.ContainsFunc(holder, func(h Grant) bool {
+			if !Match(h.Code, t.Code) {
+				return false
+			}
+			for _, condition := range h.Conditions {
+				if !slices.Contains(t.Conditions, condition) {
+					return false
+				}
+			}
+			return true
+		})
+		if !covered && !slices.Contains(uncovered, t.Code) {
+			uncovered = append(uncovered, t.Code)
+		}
+	}
+	return uncovered
+}
+
+// HasPermission 检查用户是否拥有指定权限，支持通配符权限（如 user:*、*:read、*）；
+// 按上下文中的请求属性对访问条件求值：指定权限自身的条件总是求值，不因经由通配符或无条件授予匹配而跳过
+func (s *Service) HasPermission(ctx context.Context, userId uint, code string) (bool, error) {
+	grants, err := s.GetUserGrants(ctx, userId)
+	if err != nil {
+		return false, err
+	}
+	if !slices.ContainsFunc(grants, func(grant Grant) bool { return Match(grant.Code, code) }) {
+		return false, nil
+	}
+
+	conditions, err := s.getPermissionConditions(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	env := s.newConditionEnv(userId, attributesFromContext(ctx))
+	return env.permits(ctx, grants, code, conditions[code]), nil
+}
+
+// getPermissionConditions 获取启用权限自身的访问条件（权限代码 -> 条件），全部用户共用一份缓存
+func (s *Service) getPermissionConditions(ctx context.Context) (map[string]string, error) {
+	conditions, version, ok := s.getCachedConditions(ctx)
+	if ok {
+		return conditions, nil
+	}
+
+	var permissions []Permission
+	err := s.db.WithContext(ctx).Model(&Permission{}).
+		Select("code", "condition_expr").
+		Where("status = ? AND condition_expr != ''", shared.StatusActive).
+		Find(&permissions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	conditions = make(map[string]string, len(permissions))
+	for _, permission := range permissions {
+		conditions[permission.Code] = permission.Condition
+	}
+	s.setCachedConditions(ctx, version, conditions)
+	return conditions, nil
+}
@@ -0,0 +1,45 @@
+package permission
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUncoveredGrants(t *testing.T) {
+	workHours := "time.hour >= 9 && time.hour < 18"
+	office := "cidr(ip, '10.0.0.0/8')"
+
+	cases := []struct {
+		name   string
+		holder []Grant
+		target []Grant
+		want   []string
+	}{
+		{"no target grants", []Grant{{Code: "user:read"}}, nil, nil},
+		{"same grants", []Grant{{Code: "user:read"}, {Code: "role:read"}}, []Grant{{Code: "user:read"}}, nil},
+		{"missing grant", []Grant{{Code: "user:read"}}, []Grant{{Code: "user:read"}, {Code: "role:update"}}, []string{"role:update"}},
+		{"no holder grants", nil, []Grant{{Code: "user:read"}}, []string{"user:read"}},
+
+		// 通配符
+		{"wildcard covers code", []Grant{{Code: "user:*"}}, []Grant{{Code: "user:session:revoke"}}, nil},
+		{"super covers all", []Grant{{Code: "*"}}, []Grant{{Code: "user:*"}, {Code: "*:read"}}, nil},
+		{"code does not cover wildcard", []Grant{{Code: "user:read"}}, []Grant{{Code: "user:*"}}, []string{"user:*"}},
+		{"narrower wildcard", []Grant{{Code: "user:*"}}, []Grant{{Code: "*"}}, []string{"*"}},
+
+		// 访问条件：发起者的条件须为目标条件的子集
+		{"unconditional covers conditional", []Grant{{Code: "user:update"}}, []Grant{{Code: "user:update", Conditions: []string{workHours}}}, nil},
+		{"conditional does not cover unconditional", []Grant{{Code: "user:update", Conditions: []string{workHours}}}, []Grant{{Code: "user:update"}}, []string{"user:update"}},
+		{"same condition", []Grant{{Code: "user:update", Conditions: []string{workHours}}}, []Grant{{Code: "user:update", Conditions: []string{office, workHours}}}, nil},
+		{"different condition", []Grant{{Code: "user:update", Conditions: []string{office}}}, []Grant{{Code: "user:update", Conditions: []string{workHours}}}, []string{"user:update"}},
+		{"any holder grant covers", []Grant{{Code: "user:update", Conditions: []string{office}}, {Code: "user:*"}}, []Grant{{Code: "user:update"}}, nil},
+
+		// 同一权限的多次授予只列出一次
+		{"duplicate codes", nil, []Grant{{Code: "user:update", Conditions: []string{office}}, {Code: "user:update", Conditions: []string{workHours}}}, []string{"user:update"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, uncoveredGrants(c.holder, c.target))
+		})
+	}
+}
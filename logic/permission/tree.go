@@ -0,0 +1,115 @@
+package permission
+
+import (
+	"context"
+	"go-tpl/logic/shared"
+)
+
+// Tree 获取完整的权限树，包含禁用的权限
+func (s *Service) Tree(ctx context.Context) ([]*Permission, error) {
+	list, err := s.listOrdered(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return buildTree(list), nil
+}
+
+// UserTree 获取按用户有效权限裁剪的权限树，供前端渲染导航。
+// 仅保留启用且被授予（含通配符）的权限，未授予或禁用的权限连同其下级一并隐藏
+func (s *Service) UserTree(ctx context.Context, userId uint) ([]*Permission, error) {
+	codes, err := s.GetUserPermissions(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	list, err := s.listOrdered(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[uint]*Permission, len(list))
+	for _, p := range list {
+		nodes[p.ID] = p
+	}
+
+	// visible 判断权限及其全部上级是否可见，上级不存在时视为顶级权限
+	visible := make(map[uint]bool, len(list))
+	var check func(p *Permission, depth int) bool
+	check = func(p *Permission, depth int) bool {
+		if v, ok := visible[p.ID]; ok {
+			return v
+		}
+		v := p.Status == shared.StatusActive && MatchAny(codes, p.Code)
+		if parent, ok := nodes[p.ParentID]; v && ok && depth < len(list) {
+			v = check(parent, depth+1)
+		}
+		visible[p.ID] = v
+		return v
+	}
+
+	granted := make([]*Permission, 0)
+	for _, p := range list {
+		if check(p, 0) {
+			granted = append(granted, p)
+		}
+	}
+	return buildTree(granted), nil
+}
+
+// checkParent 校验上级权限存在，且不是自身或自身的下级权限
+func (s *Service) checkParent(ctx context.Context, id, parentId uint) error {
+	if parentId == 0 {
+		return nil
+	}
+	if parentId == id {
+		return shared.ErrPermissionCycle
+	}
+	if _, err := s.Get(ctx, parentId); err != nil {
+		return err
+	}
+
+	list, err := s.listOrdered(ctx)
+	if err != nil {
+		return err
+	}
+	parents := make(map[uint]uint, len(list))
+	for _, p := range list {
+		parents[p.ID] = p.ParentID
+	}
+
+	// 沿上级权限向上查找，经过自身说明形成循环
+	visited := make(map[uint]bool)
+	for cur := parentId; cur != 0 && !visited[cur]; cur = parents[cur] {
+		if cur == id {
+			return shared.ErrPermissionCycle
+		}
+		visited[cur] = true
+	}
+	return nil
+}
+
+// listOrdered 按排序获取全部权限
+func (s *Service) listOrdered(ctx context.Context) ([]*Permission, error) {
+	var list []*Permission
+	if err := s.db.WithContext(ctx).Order("sort, id").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// buildTree 按 ParentID 组装权限树，上级不在列表中的权限作为顶级节点
+func buildTree(list []*Permission) []*Permission {
+	nodes := make(map[uint]*Permission, len(list))
+	for _, p := range list {
+		nodes[p.ID] = p
+	}
+
+	roots := make([]*Permission, 0)
+	for _, p := range list {
+		if parent, ok := nodes[p.ParentID]; ok {
+			parent.Children = append(parent.Children, p)
+		} else {
+			roots = append(roots, p)
+		}
+	}
+	return roots
+}
@@ -0,0 +1,64 @@
+package role
+
+import (
+	"context"
+	"go-tpl/logic/dept"
+	"go-tpl/logic/shared"
+	"go-tpl/web/types"
+
+	"gorm.io/gorm"
+)
+
+// SetDataScope 设置角色的数据范围，自定义范围时同时设置可见的部门
+func (s *Service) SetDataScope(ctx context.Context, roleId uint, req types.SetRoleDataScopeReq) error {
+	if _, err := s.Get(ctx, roleId); err != nil {
+		return err
+	}
+
+	var deptIds []uint
+	if req.DataScope == shared.DataScopeCustom {
+		deptIds = uniqueIds(req.DeptIds)
+		if len(deptIds) == 0 {
+			return shared.ErrInvalidParam
+		}
+
+		var count int64
+		if err := s.db.WithContext(ctx).Model(&dept.Department{}).Where("id IN ?", deptIds).Count(&count).Error; err != nil {
+			return err
+		}
+		if int(count) != len(deptIds) {
+			return shared.ErrRecordNotFound
+		}
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Role{}).Where("id = ?", roleId).Update("data_scope", req.DataScope).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("role_id = ?", roleId).Delete(&RoleDept{}).Error; err != nil {
+			return err
+		}
+		if len(deptIds) == 0 {
+			return nil
+		}
+
+		roleDepts := make([]RoleDept, len(deptIds))
+		for i, deptId := range deptIds {
+			roleDepts[i] = RoleDept{RoleID: roleId, DeptID: deptId}
+		}
+		return tx.Create(&roleDepts).Error
+	})
+}
+
+// getDeptIds 获取角色自定义数据范围的部门
+func (s *Service) getDeptIds(ctx context.Context, roleId uint) ([]uint, error) {
+	var deptIds []uint
+	err := s.db.WithContext(ctx).Model(&RoleDept{}).
+		Where("role_id = ?", roleId).
+		Order("dept_id").
+		Pluck("dept_id", &deptIds).Error
+	if err != nil {
+		return nil, err
+	}
+	return deptIds, nil
+}
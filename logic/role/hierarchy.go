@@ -0,0 +1,163 @@
+package role
+
+import (
+	"context"
+	"fmt"
+	"go-tpl/logic/shared"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+// SetParents 设置角色的上级角色，覆盖原有设置，形成循环继承时返回错误
+func (s *Service) SetParents(ctx context.Context, roleId uint, parentIds []uint) error {
+	if _, err := s.Get(ctx, roleId); err != nil {
+		return err
+	}
+
+	parentIds = uniqueIds(parentIds)
+	if len(parentIds) > 0 {
+		var count int64
+		if err := s.db.WithContext(ctx).Model(&Role{}).Where("id IN ?", parentIds).Count(&count).Error; err != nil {
+			return err
+		}
+		if int(count) != len(parentIds) {
+			return shared.ErrRecordNotFound
+		}
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		parents, err := loadParents(tx)
+		if err != nil {
+			return err
+		}
+
+		// 上级角色不能是自身或自身的后代
+		parents[roleId] = nil
+		for _, parentId := range parentIds {
+			if parentId == roleId || slices.Contains(ancestors(parents, parentId), roleId) {
+				return shared.ErrRoleCycle.WithDetail(fmt.Sprint(parentId))
+			}
+		}
+
+		if err = tx.Where("role_id = ?", roleId).Delete(&RoleParent{}).Error; err != nil {
+			return err
+		}
+		if len(parentIds) == 0 {
+			return nil
+		}
+
+		roleParents := make([]RoleParent, len(parentIds))
+		for i, parentId := range parentIds {
+			roleParents[i] = RoleParent{RoleID: roleId, ParentID: parentId}
+		}
+		return tx.Create(&roleParents).Error
+	})
+	if err != nil {
+		return err
+	}
+	return shared.InvalidateAllPermissions(ctx, s.redis)
+}
+
+// getParentIds 获取角色的直接上级角色
+func (s *Service) getParentIds(ctx context.Context, roleId uint) ([]uint, error) {
+	parentIds := make([]uint, 0)
+	err := s.db.WithContext(ctx).Model(&RoleParent{}).
+		Where("role_id = ?", roleId).
+		Order("parent_id").
+		Pluck("parent_id", &parentIds).Error
+	if err != nil {
+		return nil, err
+	}
+	return parentIds, nil
+}
+
+// ExpandActive 返回角色及其全部祖先中启用状态的角色，用于权限解析
+// 禁用的角色既不提供权限，也不向下传递其祖先的权限
+func (s *Service) ExpandActive(ctx context.Context, roleIds []uint) ([]uint, error) {
+	if len(roleIds) == 0 {
+		return nil, nil
+	}
+
+	var activeIds []uint
+	if err := s.db.WithContext(ctx).Model(&Role{}).Where("status = ?", shared.StatusActive).Pluck("id", &activeIds).Error; err != nil {
+		return nil, err
+	}
+	parents, err := loadParents(s.db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	active := make(map[uint]bool, len(activeIds))
+	for _, id := range activeIds {
+		active[id] = true
+	}
+	return expandActive(parents, active, roleIds), nil
+}
+
+// expandActive 从给定角色出发沿继承关系遍历，遇到未启用的角色时不再向上展开，存在环时也能结束
+func expandActive(parents map[uint][]uint, active map[uint]bool, roleIds []uint) []uint {
+	var (
+		result  []uint
+		visited = make(map[uint]bool)
+		queue   = slices.Clone(roleIds)
+	)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if visited[id] || !active[id] {
+			continue
+		}
+		visited[id] = true
+		result = append(result, id)
+		queue = append(queue, parents[id]...)
+	}
+	return result
+}
+
+// loadParents 加载全部角色继承关系，角色数量有限，整体加载后在内存中遍历
+func loadParents(db *gorm.DB) (map[uint][]uint, error) {
+	var roleParents []RoleParent
+	if err := db.Find(&roleParents).Error; err != nil {
+		return nil, err
+	}
+
+	parents := make(map[uint][]uint)
+	for _, rp := range roleParents {
+		parents[rp.RoleID] = append(parents[rp.RoleID], rp.ParentID)
+	}
+	return parents, nil
+}
+
+// ancestors 返回角色的全部祖先，已访问的节点不再重复遍历，数据中存在环时也能结束
+func ancestors(parents map[uint][]uint, roleId uint) []uint {
+	var (
+		result  []uint
+		visited = map[uint]bool{roleId: true}
+		queue   = slices.Clone(parents[roleId])
+	)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+		result = append(result, id)
+		queue = append(queue, parents[id]...)
+	}
+	return result
+}
+
+// uniqueIds 去重并保持原有顺序
+func uniqueIds(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
@@ -0,0 +1,61 @@
+package role
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAncestors(t *testing.T) {
+	cases := []struct {
+		name    string
+		parents map[uint][]uint
+		roleId  uint
+		want    []uint
+	}{
+		{"no parents", map[uint][]uint{}, 1, nil},
+		{"chain", map[uint][]uint{1: {2}, 2: {3}}, 1, []uint{2, 3}},
+		{"diamond", map[uint][]uint{1: {2, 3}, 2: {4}, 3: {4}}, 1, []uint{2, 3, 4}},
+		{"from middle", map[uint][]uint{1: {2}, 2: {3}}, 2, []uint{3}},
+		// 环：自身不计入祖先，遍历能够结束
+		{"self loop", map[uint][]uint{1: {1}}, 1, nil},
+		{"cycle", map[uint][]uint{1: {2}, 2: {3}, 3: {1}}, 1, []uint{2, 3}},
+		{"cycle above", map[uint][]uint{1: {2}, 2: {3}, 3: {2}}, 1, []uint{2, 3}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, ancestors(c.parents, c.roleId))
+		})
+	}
+}
+
+func TestExpandActive(t *testing.T) {
+	all := map[uint]bool{1: true, 2: true, 3: true, 4: true}
+
+	cases := []struct {
+		name    string
+		parents map[uint][]uint
+		active  map[uint]bool
+		roleIds []uint
+		want    []uint
+	}{
+		{"no roles", map[uint][]uint{1: {2}}, all, nil, nil},
+		{"chain", map[uint][]uint{1: {2}, 2: {3}}, all, []uint{1}, []uint{1, 2, 3}},
+		{"shared ancestor", map[uint][]uint{1: {3}, 2: {3}}, all, []uint{1, 2}, []uint{1, 2, 3}},
+		{"duplicate roles", map[uint][]uint{}, all, []uint{1, 1}, []uint{1}},
+		// 禁用的角色不提供权限，也不向下传递其祖先
+		{"inactive role", map[uint][]uint{1: {2}}, map[uint]bool{2: true}, []uint{1}, nil},
+		{"inactive parent", map[uint][]uint{1: {2}, 2: {3}}, map[uint]bool{1: true, 3: true}, []uint{1}, []uint{1}},
+		{"inactive on one path", map[uint][]uint{1: {2, 3}, 2: {4}, 3: {4}}, map[uint]bool{1: true, 3: true, 4: true}, []uint{1}, []uint{1, 3, 4}},
+		{"unknown role", map[uint][]uint{}, all, []uint{9}, nil},
+		// 环：每个角色只出现一次，遍历能够结束
+		{"self loop", map[uint][]uint{1: {1}}, all, []uint{1}, []uint{1}},
+		{"cycle", map[uint][]uint{1: {2}, 2: {3}, 3: {1}}, all, []uint{2}, []uint{2, 3, 1}},
+		{"cycle through inactive", map[uint][]uint{1: {2}, 2: {3}, 3: {1}}, map[uint]bool{1: true, 2: true}, []uint{1}, []uint{1, 2}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, expandActive(c.parents, c.active, c.roleIds))
+		})
+	}
+}
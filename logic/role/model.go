@@ -10,7 +10,10 @@ type Role struct {
 	ID          uint           `gorm:"primaryKey" json:"id"`
 	Name        string         `gorm:"uniqueIndex;size:50;not null" json:"name"`
 	Description string         `gorm:"size:255" json:"description"`
-	Status      int8           `gorm:"default:1" json:"status"` // 1-正常, 0-禁用
+	Status      int8           `gorm:"default:1" json:"status"`     // 1-正常, 0-禁用
+	DataScope   int8           `gorm:"default:1" json:"data_scope"` // 数据范围: 1-全部, 2-自定义部门, 3-本部门, 4-本部门及下级, 5-仅本人
+	ParentIds   []uint         `gorm:"-" json:"parent_ids"`         // 上级角色，继承其全部权限
+	DeptIds     []uint         `gorm:"-" json:"dept_ids,omitempty"` // 自定义数据范围的部门
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
@@ -20,12 +23,33 @@ func (Role) TableName() string {
 	return "roles"
 }
 
-// RolePermission 角色权限关联表
+// RolePermission 角色权限关联表，Condition 为仅对该角色生效的附加访问条件
 type RolePermission struct {
-	RoleID       uint `gorm:"column:role_id" json:"role_id"`
-	PermissionID uint `gorm:"column:permission_id" json:"permission_id"`
+	RoleID       uint   `gorm:"column:role_id" json:"role_id"`
+	PermissionID uint   `gorm:"column:permission_id" json:"permission_id"`
+	Condition    string `gorm:"column:condition_expr;size:1024" json:"condition"`
 }
 
 func (RolePermission) TableName() string {
 	return "role_permissions"
 }
+
+// RoleParent 角色继承关系，角色继承上级角色（及其祖先）的全部权限
+type RoleParent struct {
+	RoleID   uint `gorm:"column:role_id" json:"role_id"`
+	ParentID uint `gorm:"column:parent_id" json:"parent_id"`
+}
+
+func (RoleParent) TableName() string {
+	return "role_parents"
+}
+
+// RoleDept 角色自定义数据范围的部门
+type RoleDept struct {
+	RoleID uint `gorm:"column:role_id" json:"role_id"`
+	DeptID uint `gorm:"column:dept_id" json:"dept_id"`
+}
+
+func (RoleDept) TableName() string {
+	return "role_depts"
+}
@@ -6,17 +6,22 @@ import (
 	"go-tpl/logic/shared"
 	"go-tpl/logic/user"
 	"go-tpl/web/types"
+	"slices"
 
+	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Service struct {
-	db *gorm.DB
+	db    *gorm.DB
+	redis *redis.Client
 }
 
-func NewService(db *gorm.DB) *Service {
+func NewService(db *gorm.DB, redis *redis.Client) *Service {
 	return &Service{
-		db: db,
+		db:    db,
+		redis: redis,
 	}
 }
 
@@ -55,6 +60,18 @@ func (s *Service) Get(ctx context.Context, id uint) (*Role, error) {
 		}
 		return nil, err
 	}
+
+	parentIds, err := s.getParentIds(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	role.ParentIds = parentIds
+
+	if role.DataScope == shared.DataScopeCustom {
+		if role.DeptIds, err = s.getDeptIds(ctx, id); err != nil {
+			return nil, err
+		}
+	}
 	return &role, nil
 }
 
@@ -114,7 +131,13 @@ func (s *Service) Update(ctx context.Context, id uint, req types.UpdateRoleReq)
 		return shared.ErrInvalidParam
 	}
 
-	return s.db.WithContext(ctx).Model(&Role{}).Where("id = ?", id).Updates(updates).Error
+	if err = s.db.WithContext(ctx).Model(&Role{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+		return err
+	}
+	if req.Status != nil {
+		return shared.InvalidateAllPermissions(ctx, s.redis)
+	}
+	return nil
 }
 
 // Delete 删除角色
@@ -126,7 +149,7 @@ func (s *Service) Delete(ctx context.Context, id uint) error {
 	}
 
 	// 开启事务，同时删除角色权限关联
-	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// 删除角色权限关联
 		if err = tx.Where("role_id = ?", id).Delete(&RolePermission{}).Error; err != nil {
 			return err
@@ -137,9 +160,23 @@ func (s *Service) Delete(ctx context.Context, id uint) error {
 			return err
 		}
 
+		// 删除角色继承关系，其下级角色不再继承该角色的权限
+		if err = tx.Where("role_id = ? OR parent_id = ?", id, id).Delete(&RoleParent{}).Error; err != nil {
+			return err
+		}
+
+		// 删除角色数据范围
+		if err = tx.Where("role_id = ?", id).Delete(&RoleDept{}).Error; err != nil {
+			return err
+		}
+
 		// 删除角色
 		return tx.Delete(&Role{}, id).Error
 	})
+	if err != nil {
+		return err
+	}
+	return shared.InvalidateAllPermissions(ctx, s.redis)
 }
 
 // UpdateStatus 更新角色状态
@@ -149,55 +186,241 @@ func (s *Service) UpdateStatus(ctx context.Context, id uint, status int8) error
 		return err
 	}
 
-	return s.db.WithContext(ctx).Model(&Role{}).Where("id = ?", id).Update("status", status).Error
+	if err = s.db.WithContext(ctx).Model(&Role{}).Where("id = ?", id).Update("status", status).Error; err != nil {
+		return err
+	}
+	return shared.InvalidateAllPermissions(ctx, s.redis)
 }
 
-// GetRolePermissions 获取角色权限列表
-func (s *Service) GetRolePermissions(ctx context.Context, roleId uint) ([]uint, error) {
-	var rolePermissions []RolePermission
-	err := s.db.WithContext(ctx).Where("role_id = ?", roleId).Find(&rolePermissions).Error
-	if err != nil {
-		return nil, err
+// GetRolePermissions 获取角色权限列表，inherited 为 true 时包含从祖先角色继承的权限（与权限校验一致，仅经由启用的角色继承）
+func (s *Service) GetRolePermissions(ctx context.Context, roleId uint, inherited bool) ([]uint, error) {
+	roleIds := []uint{roleId}
+	if inherited {
+		parentIds, err := s.getParentIds(ctx, roleId)
+		if err != nil {
+			return nil, err
+		}
+		ancestorIds, err := s.ExpandActive(ctx, parentIds)
+		if err != nil {
+			return nil, err
+		}
+		roleIds = append(roleIds, ancestorIds...)
 	}
 
-	permissionIds := make([]uint, len(rolePermissions))
-	for i, rp := range rolePermissions {
-		permissionIds[i] = rp.PermissionID
+	permissionIds := make([]uint, 0)
+	err := s.db.WithContext(ctx).Model(&RolePermission{}).
+		Where("role_id IN ?", roleIds).
+		Distinct("permission_id").
+		Order("permission_id").
+		Pluck("permission_id", &permissionIds).Error
+	if err != nil {
+		return nil, err
 	}
 	return permissionIds, nil
 }
 
-// AssignPermissions 为角色分配权限
-func (s *Service) AssignPermissions(ctx context.Context, roleId uint, permissionIds []uint) error {
+// AssignPermissions 为角色分配权限，覆盖现有权限；权限须存在且为启用状态，仅增删有差异的权限
+func (s *Service) AssignPermissions(ctx context.Context, roleId uint, permissionIds []uint) (*shared.AssignResult, error) {
 	// 检查角色是否存在
 	_, err := s.Get(ctx, roleId)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	permissionIds = uniqueIds(permissionIds)
+	if err = s.validatePermissions(ctx, permissionIds); err != nil {
+		return nil, err
 	}
 
-	// 开启事务
-	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// 删除角色现有权限
-		if err = tx.Where("role_id = ?", roleId).Delete(&RolePermission{}).Error; err != nil {
+	result := shared.NewAssignResult()
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		existing, err := lockRolePermissions(tx, roleId)
+		if err != nil {
 			return err
 		}
 
-		// 分配新权限
-		if len(permissionIds) > 0 {
-			rolePermissions := make([]RolePermission, len(permissionIds))
-			for i, permissionId := range permissionIds {
-				rolePermissions[i] = RolePermission{
-					RoleID:       roleId,
-					PermissionID: permissionId,
-				}
-			}
-			if err = tx.Create(&rolePermissions).Error; err != nil {
+		result.Added, result.Removed = shared.DiffIds(existing, permissionIds)
+		if len(result.Removed) > 0 {
+			if err = tx.Where("role_id = ? AND permission_id IN ?", roleId, result.Removed).Delete(&RolePermission{}).Error; err != nil {
 				return err
 			}
 		}
 
+		added := make([]RolePermission, 0, len(result.Added))
+		for _, permissionId := range result.Added {
+			added = append(added, RolePermission{RoleID: roleId, PermissionID: permissionId})
+		}
+		if len(added) > 0 {
+			return tx.Create(&added).Error
+		}
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.invalidateIfChanged(ctx, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// AddPermission 为角色增加单个权限，权限已分配时不做修改
+func (s *Service) AddPermission(ctx context.Context, roleId, permissionId uint) (*shared.AssignResult, error) {
+	if _, err := s.Get(ctx, roleId); err != nil {
+		return nil, err
+	}
+	if err := s.validatePermissions(ctx, []uint{permissionId}); err != nil {
+		return nil, err
+	}
+
+	result := shared.NewAssignResult()
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		existing, err := lockRolePermissions(tx, roleId)
+		if err != nil {
+			return err
+		}
+		if slices.Contains(existing, permissionId) {
+			return nil
+		}
+
+		result.Added = append(result.Added, permissionId)
+		return tx.Create(&RolePermission{RoleID: roleId, PermissionID: permissionId}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.invalidateIfChanged(ctx, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// RemovePermission 移除角色的单个权限，权限未分配时不做修改
+func (s *Service) RemovePermission(ctx context.Context, roleId, permissionId uint) (*shared.AssignResult, error) {
+	if _, err := s.Get(ctx, roleId); err != nil {
+		return nil, err
+	}
+
+	deleted := s.db.WithContext(ctx).Where("role_id = ? AND permission_id = ?", roleId, permissionId).Delete(&RolePermission{})
+	if deleted.Error != nil {
+		return nil, deleted.Error
+	}
+
+	result := shared.NewAssignResult()
+	if deleted.RowsAffected > 0 {
+		result.Removed = append(result.Removed, permissionId)
+	}
+	if err := s.invalidateIfChanged(ctx, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetPermissionConditions 获取角色附带访问条件的权限关联
+func (s *Service) GetPermissionConditions(ctx context.Context, roleId uint) ([]RolePermission, error) {
+	if _, err := s.Get(ctx, roleId); err != nil {
+		return nil, err
+	}
+
+	links := make([]RolePermission, 0)
+	err := s.db.WithContext(ctx).
+		Where("role_id = ? AND condition_expr <> ''", roleId).
+		Order("permission_id").
+		Find(&links).Error
+	if err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// SetPermissionCondition 设置角色权限关联的附加访问条件，仅对经由该角色（及继承该角色的下级角色）获得的权限生效，空字符串表示清除条件
+func (s *Service) SetPermissionCondition(ctx context.Context, roleId, permissionId uint, condition string) error {
+	if _, err := s.Get(ctx, roleId); err != nil {
+		return err
+	}
+	if condition != "" {
+		if _, err := shared.CompileCondition(condition); err != nil {
+			return err
+		}
+	}
+
+	// 条件未变化时更新影响行数为 0，因此先单独检查关联是否存在
+	var count int64
+	err := s.db.WithContext(ctx).Model(&RolePermission{}).
+		Where("role_id = ? AND permission_id = ?", roleId, permissionId).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return shared.ErrRecordNotFound
+	}
+
+	err = s.db.WithContext(ctx).Model(&RolePermission{}).
+		Where("role_id = ? AND permission_id = ?", roleId, permissionId).
+		Update("condition_expr", condition).Error
+	if err != nil {
+		return err
+	}
+	return shared.InvalidateAllPermissions(ctx, s.redis)
+}
+
+// validatePermissions 校验权限均存在且为启用状态，否则返回不合法的权限 ID
+func (s *Service) validatePermissions(ctx context.Context, permissionIds []uint) error {
+	if len(permissionIds) == 0 {
+		return nil
+	}
+
+	var found []uint
+	err := s.db.WithContext(ctx).Table("permissions").
+		Where("id IN ? AND status = ? AND deleted_at IS NULL", permissionIds, shared.StatusActive).
+		Pluck("id", &found).Error
+	if err != nil {
+		return err
+	}
+	if invalid := shared.MissingIds(permissionIds, found); len(invalid) > 0 {
+		return shared.ErrPermissionInvalid.WithDetail(shared.JoinIds(invalid))
+	}
+	return nil
+}
+
+// lockRolePermissions 锁定角色记录并读取其现有权限，串行化同一角色的并发权限变更
+func lockRolePermissions(tx *gorm.DB, roleId uint) ([]uint, error) {
+	var locked Role
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&locked, roleId).Error; err != nil {
+		return nil, err
+	}
+
+	var existing []uint
+	if err := tx.Model(&RolePermission{}).Where("role_id = ?", roleId).Pluck("permission_id", &existing).Error; err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// invalidateIfChanged 权限分配有变更时使全部用户的权限缓存失效，权限会被下级角色继承，影响范围不止本角色的用户
+func (s *Service) invalidateIfChanged(ctx context.Context, result *shared.AssignResult) error {
+	if !result.Changed() {
+		return nil
+	}
+	return shared.InvalidateAllPermissions(ctx, s.redis)
+}
+
+// ListUserRoles 获取用户当前生效的角色
+func (s *Service) ListUserRoles(ctx context.Context, userId uint) ([]Role, error) {
+	var roles []Role
+	err := s.db.WithContext(ctx).
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", userId).
+		Scopes(user.ActiveRoles).
+		Order("roles.id").
+		Find(&roles).Error
+	if err != nil {
+		return nil, err
+	}
+	return roles, nil
 }
 
 // GetRoleUsers 获取角色用户列表
@@ -0,0 +1,159 @@
+package role
+
+import (
+	"context"
+	"go-tpl/logic/shared"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func setupTestService(t *testing.T) (*Service, sqlmock.Sqlmock, *miniredis.Miniredis) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{Conn: db, SkipInitializeWithVersion: true}), &gorm.Config{})
+	require.NoError(t, err)
+
+	mr := miniredis.RunT(t)
+	return NewService(gormDB, redis.NewClient(&redis.Options{Addr: mr.Addr()})), mock, mr
+}
+
+// expectRole 期望读取角色及其上级角色
+func expectRole(mock sqlmock.Sqlmock, roleId uint) {
+	mock.ExpectQuery("SELECT \\* FROM `roles`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "status", "data_scope"}).AddRow(roleId, "editor", shared.StatusActive, shared.DataScopeAll))
+	mock.ExpectQuery("SELECT `parent_id` FROM `role_parents`").
+		WillReturnRows(sqlmock.NewRows([]string{"parent_id"}))
+}
+
+// expectValidPermissions 期望校验权限，返回存在且启用的权限
+func expectValidPermissions(mock sqlmock.Sqlmock, found ...uint) {
+	rows := sqlmock.NewRows([]string{"id"})
+	for _, id := range found {
+		rows.AddRow(id)
+	}
+	mock.ExpectQuery("SELECT `id` FROM `permissions`").WillReturnRows(rows)
+}
+
+// expectLocked 期望在事务中先锁定角色，再读取其现有权限
+func expectLocked(mock sqlmock.Sqlmock, roleId uint, existing ...uint) {
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT `id` FROM `roles` .* FOR UPDATE").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(roleId))
+	rows := sqlmock.NewRows([]string{"permission_id"})
+	for _, id := range existing {
+		rows.AddRow(id)
+	}
+	mock.ExpectQuery("SELECT `permission_id` FROM `role_permissions`").WillReturnRows(rows)
+}
+
+func TestAssignPermissions(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Diff", func(t *testing.T) {
+		s, mock, mr := setupTestService(t)
+		expectRole(mock, 1)
+		expectValidPermissions(mock, 2, 4, 5)
+		expectLocked(mock, 1, 1, 2, 3)
+		mock.ExpectExec("DELETE FROM `role_permissions` WHERE role_id = \\? AND permission_id IN \\(\\?,\\?\\)").
+			WithArgs(1, 1, 3).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec("INSERT INTO `role_permissions`").
+			WithArgs(1, 4, "", 1, 5, "").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		result, err := s.AssignPermissions(ctx, 1, []uint{4, 2, 5, 4})
+		require.NoError(t, err)
+		assert.Equal(t, []uint{4, 5}, result.Added)
+		assert.Equal(t, []uint{1, 3}, result.Removed)
+		require.NoError(t, mock.ExpectationsWereMet())
+
+		// 有变更时使全部用户的权限缓存失效
+		version, err := mr.Get(shared.CachePermissionVersion)
+		require.NoError(t, err)
+		assert.Equal(t, "1", version)
+	})
+
+	t.Run("Unchanged", func(t *testing.T) {
+		s, mock, mr := setupTestService(t)
+		expectRole(mock, 1)
+		expectValidPermissions(mock, 1, 2)
+		expectLocked(mock, 1, 2, 1)
+		mock.ExpectCommit()
+
+		result, err := s.AssignPermissions(ctx, 1, []uint{1, 2})
+		require.NoError(t, err)
+		assert.False(t, result.Changed())
+		require.NoError(t, mock.ExpectationsWereMet())
+		assert.False(t, mr.Exists(shared.CachePermissionVersion))
+	})
+
+	t.Run("InvalidPermissions", func(t *testing.T) {
+		// 不存在或禁用的权限在开启事务前被拒绝
+		s, mock, _ := setupTestService(t)
+		expectRole(mock, 1)
+		expectValidPermissions(mock, 2)
+
+		_, err := s.AssignPermissions(ctx, 1, []uint{9, 2, 3})
+		var e shared.Error
+		require.ErrorAs(t, err, &e)
+		assert.Equal(t, shared.ErrPermissionInvalid.Code, e.Code)
+		assert.Equal(t, shared.ErrPermissionInvalid.WithDetail("3, 9").Msg, e.Msg)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAddPermission(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Added", func(t *testing.T) {
+		s, mock, mr := setupTestService(t)
+		expectRole(mock, 1)
+		expectValidPermissions(mock, 3)
+		expectLocked(mock, 1, 1, 2)
+		mock.ExpectExec("INSERT INTO `role_permissions`").
+			WithArgs(1, 3, "").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		result, err := s.AddPermission(ctx, 1, 3)
+		require.NoError(t, err)
+		assert.Equal(t, []uint{3}, result.Added)
+		assert.Empty(t, result.Removed)
+		require.NoError(t, mock.ExpectationsWereMet())
+		assert.True(t, mr.Exists(shared.CachePermissionVersion))
+	})
+
+	t.Run("AlreadyAssigned", func(t *testing.T) {
+		s, mock, mr := setupTestService(t)
+		expectRole(mock, 1)
+		expectValidPermissions(mock, 2)
+		expectLocked(mock, 1, 1, 2)
+		mock.ExpectCommit()
+
+		result, err := s.AddPermission(ctx, 1, 2)
+		require.NoError(t, err)
+		assert.False(t, result.Changed())
+		require.NoError(t, mock.ExpectationsWereMet())
+		assert.False(t, mr.Exists(shared.CachePermissionVersion))
+	})
+
+	t.Run("DisabledPermission", func(t *testing.T) {
+		s, mock, _ := setupTestService(t)
+		expectRole(mock, 1)
+		expectValidPermissions(mock)
+
+		_, err := s.AddPermission(ctx, 1, 2)
+		var e shared.Error
+		require.ErrorAs(t, err, &e)
+		assert.Equal(t, shared.ErrPermissionInvalid.Code, e.Code)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,61 @@
+package shared
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// AssignResult 分配操作的结果，列出实际新增和移除的 ID
+type AssignResult struct {
+	Added   []uint `json:"added"`
+	Removed []uint `json:"removed"`
+}
+
+// NewAssignResult 创建空的分配结果，序列化时输出空列表而非 null
+func NewAssignResult() *AssignResult {
+	return &AssignResult{Added: make([]uint, 0), Removed: make([]uint, 0)}
+}
+
+// Changed 是否有实际变更
+func (r *AssignResult) Changed() bool {
+	return len(r.Added) > 0 || len(r.Removed) > 0
+}
+
+// DiffIds 比较现有 ID 与目标 ID，返回需新增（按 wanted 顺序）和需移除（按 current 顺序）的 ID
+func DiffIds(current, wanted []uint) (added, removed []uint) {
+	added = make([]uint, 0)
+	for _, id := range wanted {
+		if !slices.Contains(current, id) {
+			added = append(added, id)
+		}
+	}
+	removed = make([]uint, 0)
+	for _, id := range current {
+		if !slices.Contains(wanted, id) {
+			removed = append(removed, id)
+		}
+	}
+	return added, removed
+}
+
+// MissingIds 返回 ids 中不在 found 内的 ID，去重并升序排列
+func MissingIds(ids, found []uint) []uint {
+	missing := make([]uint, 0)
+	for _, id := range ids {
+		if !slices.Contains(found, id) {
+			missing = append(missing, id)
+		}
+	}
+	slices.Sort(missing)
+	return slices.Compact(missing)
+}
+
+// JoinIds 以逗号连接 ID，用于错误详情
+func JoinIds(ids []uint) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,63 @@
+package shared
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffIds(t *testing.T) {
+	cases := []struct {
+		name        string
+		current     []uint
+		wanted      []uint
+		wantAdded   []uint
+		wantRemoved []uint
+	}{
+		{"both empty", nil, nil, []uint{}, []uint{}},
+		{"add all", nil, []uint{3, 1}, []uint{3, 1}, []uint{}},
+		{"remove all", []uint{1, 2}, nil, []uint{}, []uint{1, 2}},
+		{"unchanged", []uint{1, 2}, []uint{2, 1}, []uint{}, []uint{}},
+		{"add and remove", []uint{1, 2, 3}, []uint{4, 2, 5}, []uint{4, 5}, []uint{1, 3}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			added, removed := DiffIds(c.current, c.wanted)
+			assert.Equal(t, c.wantAdded, added)
+			assert.Equal(t, c.wantRemoved, removed)
+		})
+	}
+}
+
+func TestMissingIds(t *testing.T) {
+	cases := []struct {
+		name  string
+		ids   []uint
+		found []uint
+		want  []uint
+	}{
+		{"none requested", nil, []uint{1}, []uint{}},
+		{"all found", []uint{2, 1}, []uint{1, 2}, []uint{}},
+		{"none found", []uint{2, 1}, nil, []uint{1, 2}},
+		// 去重并升序排列
+		{"sorted and unique", []uint{9, 3, 1, 9, 3}, []uint{1}, []uint{3, 9}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, MissingIds(c.ids, c.found))
+		})
+	}
+}
+
+func TestAssignResult(t *testing.T) {
+	result := NewAssignResult()
+	assert.False(t, result.Changed())
+	assert.NotNil(t, result.Added)
+	assert.NotNil(t, result.Removed)
+
+	result.Removed = append(result.Removed, 1)
+	assert.True(t, result.Changed())
+
+	assert.Equal(t, "", JoinIds(nil))
+	assert.Equal(t, "3, 9", JoinIds([]uint{3, 9}))
+}
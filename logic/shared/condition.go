@@ -0,0 +1,12 @@
+package shared
+
+import "go-tpl/infra/expr"
+
+// CompileCondition 编译访问条件表达式，语法错误返回 ErrConditionInvalid
+func CompileCondition(src string) (*expr.Program, error) {
+	program, err := expr.Compile(src)
+	if err != nil {
+		return nil, ErrConditionInvalid.WithDetail(err.Error())
+	}
+	return program, nil
+}
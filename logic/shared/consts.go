@@ -3,7 +3,58 @@ package shared
 const (
 	CacheUserInfo = "user:%d"
 
+	// 登录失败计数与锁定
+	CacheLoginFailUser = "login:fail:user:%s"
+	CacheLoginFailIP   = "login:fail:ip:%s"
+	CacheLoginLockUser = "login:lock:user:%s"
+	CacheLoginLockIP   = "login:lock:ip:%s"
+
+	// 两步验证
+	CacheTwoFactorPending = "2fa:pending:%d" // 待确认的 TOTP 密钥
+	CacheTwoFactorUsed    = "2fa:used:%d:%d" // 已使用的 TOTP 时间步，防止重放
+
+	// 密码重置
+	CachePasswordResetToken    = "pwd_reset:token:%s"    // token 哈希 -> 用户 ID
+	CachePasswordResetUser     = "pwd_reset:user:%d"     // 用户当前有效的 token 哈希
+	CachePasswordResetCooldown = "pwd_reset:cooldown:%d" // 发送频率限制
+
+	// 登录会话
+	CacheSessionTouch = "session:touch:%s" // 最近活跃时间更新间隔限制
+
+	// API Key
+	CacheAPIKeyTouch = "apikey:touch:%d" // 最近使用时间更新间隔限制
+
+	// 第三方登录
+	CacheOIDCState = "oidc:state:%s" // 授权请求的 nonce 和 code_verifier
+
+	// 邮箱验证
+	CacheEmailVerifyCooldown = "email_verify:cooldown:%s" // 重发间隔限制，按邮箱哈希计数，与账户是否存在无关
+	CacheEmailVerifyCount    = "email_verify:count:%s"    // 每小时重发次数，按邮箱哈希计数
+
+	// 用户权限
+	CachePermissionUser        = "perm:grants:%d"       // 用户有效权限及其访问条件
+	CachePermissionUserVersion = "perm:user_version:%d" // 用户权限缓存版本，递增后该用户的缓存失效
+	CachePermissionVersion     = "perm:version"         // 权限缓存版本，递增后全部用户缓存失效
+	CachePermissionConditions  = "perm:conditions"      // 权限自身的访问条件，随权限缓存版本失效
+
 	// StatusActive Common status constants
 	StatusActive   = 1 // 正常
 	StatusDisabled = 0 // 禁用
+	StatusPending  = 2 // 待验证（仅用户）
+)
+
+// 角色数据范围，用户拥有多个角色时取并集
+const (
+	DataScopeAll             = 1 // 全部数据
+	DataScopeCustom          = 2 // 自定义部门
+	DataScopeDept            = 3 // 本部门
+	DataScopeDeptAndChildren = 4 // 本部门及下级部门
+	DataScopeSelf            = 5 // 仅本人
+)
+
+// 权限类型，前端据此渲染导航菜单和操作按钮
+const (
+	PermissionTypeMenu   = "menu"   // 菜单
+	PermissionTypeButton = "button" // 按钮
+	PermissionTypeAPI    = "api"    // 接口
 )
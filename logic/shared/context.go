@@ -0,0 +1,16 @@
+package shared
+
+import "context"
+
+type callerKey struct{}
+
+// WithCaller 在上下文中记录发起请求的用户，业务层据此应用数据范围
+func WithCaller(ctx context.Context, userID uint) context.Context {
+	return context.WithValue(ctx, callerKey{}, userID)
+}
+
+// CallerFromContext 获取发起请求的用户，内部调用（如登录流程）没有调用者
+func CallerFromContext(ctx context.Context) (uint, bool) {
+	userID, ok := ctx.Value(callerKey{}).(uint)
+	return userID, ok
+}
@@ -0,0 +1,22 @@
+package shared
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// incrWithTTLScript 递增计数，计数没有过期时间时设置过期时间，保证计数不会永久保留
+var incrWithTTLScript = redis.NewScript(`
+local n = redis.call("INCR", KEYS[1])
+if redis.call("PTTL", KEYS[1]) < 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return n
+`)
+
+// IncrWithTTL 原子地递增计数并在首次递增时设置过期时间，用于限流和失败计数
+func IncrWithTTL(ctx context.Context, rdb *redis.Client, key string, ttl time.Duration) (int64, error) {
+	return incrWithTTLScript.Run(ctx, rdb, []string{key}, ttl.Milliseconds()).Int64()
+}
@@ -7,6 +7,12 @@ var (
 	// 通用错误
 	ErrNoToken        = NewError(1000, "no token")
 	ErrInvalidToken   = NewError(1001, "invalid token")
+	ErrNoPermission   = NewError(1002, "无权限访问")
+	ErrTokenReused    = NewError(1003, "refresh token 已被使用")
+	ErrTokenExpired   = NewError(1004, "token 已过期")
+	ErrTokenNotActive = NewError(1005, "token 尚未生效")
+	ErrTokenAudience  = NewError(1006, "token 不属于当前应用")
+	ErrTokenSignature = NewError(1007, "token 签名无效")
 	ErrInvalidParam   = NewError(1100, "参数错误")
 	ErrRecordNotFound = NewError(1101, "数据不存在")
 
@@ -16,8 +22,55 @@ var (
 	ErrInvalidPassword  = NewError(2103, "密码格式错误")
 	ErrLoginFailed      = NewError(2104, "用户名或密码错误")
 	ErrUserDisabled     = NewError(2105, "用户已禁用")
+	ErrAccountLocked    = NewError(2106, "登录失败次数过多，账户已临时锁定")
+	ErrOldPasswordWrong = NewError(2107, "原密码错误")
 	ErrRoleExists       = NewError(2110, "角色已存在")
+	ErrRoleCycle        = NewError(2111, "角色继承不能形成循环")
+	ErrRoleInvalid      = NewError(2112, "角色不存在或已禁用")
 	ErrPermissionExists = NewError(2120, "权限已存在")
+	ErrPermissionCode   = NewError(2121, "权限代码格式错误")
+	ErrDeptCycle        = NewError(2140, "上级部门不能是自身或下级部门")
+	ErrDeptNotEmpty     = NewError(2141, "部门下存在子部门或成员，无法删除")
+
+	// 权限树、权限分配与访问条件错误
+	ErrPermissionCycle    = NewError(2122, "上级权限不能是自身或下级权限")
+	ErrPermissionNotEmpty = NewError(2123, "权限下存在子权限，无法删除")
+	ErrPermissionInvalid  = NewError(2124, "权限不存在或已禁用")
+	ErrConditionInvalid   = NewError(2125, "条件表达式错误")
+
+	// 密码策略错误
+	ErrPasswordTooShort = NewError(2130, "密码长度不足")
+	ErrPasswordTooLong  = NewError(2131, "密码过长")
+	ErrPasswordWeak     = NewError(2132, "密码必须包含")
+	ErrPasswordUserInfo = NewError(2133, "密码不能包含用户名或邮箱")
+	ErrPasswordBanned   = NewError(2134, "密码过于常见，请更换")
+	ErrPasswordReused   = NewError(2135, "不能使用最近使用过的密码")
+
+	// 认证错误
+	ErrInvalidOTP          = NewError(2201, "动态验证码错误")
+	ErrTwoFactorEnabled    = NewError(2202, "已开启两步验证")
+	ErrTwoFactorNotEnabled = NewError(2203, "未开启两步验证")
+	ErrTwoFactorExpired    = NewError(2204, "两步验证已过期，请重新开始")
+	ErrResetTokenInvalid   = NewError(2205, "重置链接无效或已过期")
+	ErrEmailNotVerified    = NewError(2206, "邮箱未验证")
+	ErrVerifyTokenInvalid  = NewError(2207, "验证链接无效或已过期")
+	ErrTooManyRequests     = NewError(2208, "请求过于频繁，请稍后再试")
+
+	// API Key 错误
+	ErrAPIKeyInvalid = NewError(2301, "API Key 无效或已过期")
+	ErrAPIKeyScope   = NewError(2302, "API Key 权限超出所属用户权限范围")
+
+	// 第三方登录错误
+	ErrOIDCProviderNotFound = NewError(2401, "登录方式不存在")
+	ErrOIDCStateInvalid     = NewError(2402, "登录请求无效或已过期")
+	ErrOIDCLoginFailed      = NewError(2403, "第三方登录失败")
+	ErrOIDCEmailNotVerified = NewError(2404, "第三方账户邮箱未验证")
+	ErrOIDCUserNotFound     = NewError(2405, "账户不存在，请联系管理员开通")
+
+	// 模拟登录错误
+	ErrImpersonateSelf  = NewError(2501, "不能模拟自己")
+	ErrImpersonateAdmin = NewError(2502, "不能模拟管理员账户")
+	ErrImpersonateScope = NewError(2503, "不能模拟权限超出自身的用户")
 )
 
 type Error struct {
@@ -35,3 +88,11 @@ func NewError(code int, msg string) Error {
 func (err Error) Error() string {
 	return err.Msg
 }
+
+// WithDetail 返回附带详细信息的同码错误
+func (err Error) WithDetail(detail string) Error {
+	return Error{
+		Code: err.Code,
+		Msg:  err.Msg + ": " + detail,
+	}
+}
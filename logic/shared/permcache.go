@@ -0,0 +1,30 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// InvalidateUserPermissions 递增指定用户的权限缓存版本，用于用户角色变更。
+// 不直接删除缓存：变更前已开始计算的读取方会在删除后回写旧结果，递增版本后旧结果的版本不再匹配
+func InvalidateUserPermissions(ctx context.Context, rdb *redis.Client, userIds ...uint) error {
+	if len(userIds) == 0 {
+		return nil
+	}
+
+	_, err := rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, userId := range userIds {
+			pipe.Incr(ctx, fmt.Sprintf(CachePermissionUserVersion, userId))
+		}
+		return nil
+	})
+	return err
+}
+
+// InvalidateAllPermissions 递增权限缓存版本，使全部用户的权限缓存失效，
+// 用于角色、权限及其关联变更（受角色继承影响的用户难以逐一计算）
+func InvalidateAllPermissions(ctx context.Context, rdb *redis.Client) error {
+	return rdb.Incr(ctx, CachePermissionVersion).Err()
+}
@@ -0,0 +1,18 @@
+package shared
+
+// MaxUserAgentLength User-Agent 最大保存长度（字符数），与会话、审计记录的字段长度一致
+const MaxUserAgentLength = 255
+
+// Truncate 将字符串截断为最多 n 个字符，按字符边界截断，不会产生不完整的 UTF-8 编码
+func Truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
@@ -0,0 +1,41 @@
+package shared
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"go-tpl/infra/jwt"
+)
+
+// RandomToken 生成 URL 安全的随机 token
+func RandomToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken 计算 token 的 SHA-256 哈希，用于只保存哈希的一次性凭证
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// TokenError 将 token 校验错误转换为业务错误，便于客户端区分过期与伪造的 token
+func TokenError(err error) error {
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return ErrTokenExpired
+	case errors.Is(err, jwt.ErrTokenNotValidYet):
+		return ErrTokenNotActive
+	case errors.Is(err, jwt.ErrTokenIssuer), errors.Is(err, jwt.ErrTokenAudience):
+		return ErrTokenAudience
+	case errors.Is(err, jwt.ErrTokenSignature):
+		return ErrTokenSignature
+	default:
+		return ErrInvalidToken
+	}
+}
@@ -0,0 +1,124 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"go-tpl/logic/shared"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+// deptScope 调用者可见的部门范围
+type deptScope struct {
+	callerID uint
+	all      bool   // 不限制
+	deptIds  []uint // 可见的部门，调用者本人始终可见
+}
+
+// allows 判断部门是否在范围内
+func (sc *deptScope) allows(deptId uint) bool {
+	return sc.all || slices.Contains(sc.deptIds, deptId)
+}
+
+// dataScope 根据上下文中的调用者及其角色的数据范围生成用户查询条件
+// 多个角色取并集，调用者始终可以看到自己；没有调用者（登录等内部流程）时不做限制
+func (s *Service) dataScope(ctx context.Context) (func(*gorm.DB) *gorm.DB, error) {
+	sc, err := s.resolveDeptScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if sc.all {
+		return noScope, nil
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		if len(sc.deptIds) == 0 {
+			return db.Where("users.id = ?", sc.callerID)
+		}
+		return db.Where("(users.id = ? OR users.dept_id IN ?)", sc.callerID, sc.deptIds)
+	}, nil
+}
+
+// checkDeptInScope 校验将用户分配到的部门在调用者的数据范围内，避免调用者把用户（包括自己）移入不可见的部门从而扩大可见范围
+func (s *Service) checkDeptInScope(ctx context.Context, deptId uint) error {
+	sc, err := s.resolveDeptScope(ctx)
+	if err != nil {
+		return err
+	}
+	if !sc.allows(deptId) {
+		return shared.ErrNoPermission.WithDetail(fmt.Sprintf("dept %d is out of data scope", deptId))
+	}
+	return nil
+}
+
+// resolveDeptScope 按调用者角色的数据范围计算可见部门
+func (s *Service) resolveDeptScope(ctx context.Context) (*deptScope, error) {
+	callerID, ok := shared.CallerFromContext(ctx)
+	if !ok {
+		return &deptScope{all: true}, nil
+	}
+
+	var roles []struct {
+		ID        uint
+		DataScope int8
+	}
+	err := s.db.WithContext(ctx).Table("roles").
+		Select("roles.id, roles.data_scope").
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ? AND roles.status = ? AND roles.deleted_at IS NULL", callerID, shared.StatusActive).
+		Scopes(ActiveRoles).
+		Scan(&roles).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		customRoleIds         []uint
+		ownDept, withChildren bool
+	)
+	for _, r := range roles {
+		switch r.DataScope {
+		case shared.DataScopeAll:
+			return &deptScope{callerID: callerID, all: true}, nil
+		case shared.DataScopeCustom:
+			customRoleIds = append(customRoleIds, r.ID)
+		case shared.DataScopeDept:
+			ownDept = true
+		case shared.DataScopeDeptAndChildren:
+			withChildren = true
+		}
+	}
+
+	var deptIds []uint
+	if len(customRoleIds) > 0 {
+		err = s.db.WithContext(ctx).Table("role_depts").Where("role_id IN ?", customRoleIds).Pluck("dept_id", &deptIds).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	if ownDept || withChildren {
+		var callerDept uint
+		err = s.db.WithContext(ctx).Model(&User{}).Select("dept_id").Where("id = ?", callerID).Scan(&callerDept).Error
+		if err != nil {
+			return nil, err
+		}
+		if callerDept != 0 {
+			if withChildren {
+				descendants, err := s.deptSvc.Descendants(ctx, []uint{callerDept})
+				if err != nil {
+					return nil, err
+				}
+				deptIds = append(deptIds, descendants...)
+			} else {
+				deptIds = append(deptIds, callerDept)
+			}
+		}
+	}
+
+	slices.Sort(deptIds)
+	return &deptScope{callerID: callerID, deptIds: slices.Compact(deptIds)}, nil
+}
+
+func noScope(db *gorm.DB) *gorm.DB {
+	return db
+}
@@ -0,0 +1,87 @@
+package user
+
+import (
+	"context"
+	"go-tpl/logic/dept"
+	"go-tpl/logic/shared"
+	"go-tpl/web/types"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      db,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{})
+	require.NoError(t, err)
+
+	return gormDB, mock
+}
+
+func TestDeptScopeAllows(t *testing.T) {
+	assert.True(t, (&deptScope{all: true}).allows(9))
+
+	sc := &deptScope{callerID: 1, deptIds: []uint{2, 3}}
+	assert.True(t, sc.allows(2))
+	assert.True(t, sc.allows(3))
+	assert.False(t, sc.allows(4))
+	assert.False(t, sc.allows(0))
+
+	assert.False(t, (&deptScope{callerID: 1}).allows(2))
+}
+
+func TestUpdateOwnDept(t *testing.T) {
+	db, mock := setupTestDB(t)
+	s := NewService(db, nil, nil, nil, nil)
+	ctx := shared.WithCaller(context.Background(), 7)
+
+	// 即使数据范围为全部数据，也不能修改自己的部门
+	mock.ExpectQuery("SELECT roles.id, roles.data_scope FROM `roles`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "data_scope"}).AddRow(1, shared.DataScopeAll))
+	mock.ExpectQuery("SELECT \\* FROM `users`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "dept_id"}).AddRow(7, "alice", 1))
+
+	deptId := uint(2)
+	err := s.Update(ctx, 7, types.UpdateUserReq{DeptID: &deptId})
+	var e shared.Error
+	require.ErrorAs(t, err, &e)
+	assert.Equal(t, shared.ErrNoPermission.Code, e.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateDeptOutOfScope(t *testing.T) {
+	db, mock := setupTestDB(t)
+	s := NewService(db, nil, nil, nil, dept.NewService(db))
+	ctx := shared.WithCaller(context.Background(), 1)
+
+	expectDeptScope := func() {
+		mock.ExpectQuery("SELECT roles.id, roles.data_scope FROM `roles`").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "data_scope"}).AddRow(2, shared.DataScopeDept))
+		mock.ExpectQuery("SELECT `dept_id` FROM `users`").
+			WillReturnRows(sqlmock.NewRows([]string{"dept_id"}).AddRow(3))
+	}
+
+	// 调用者仅能看到本部门（3），不能把本部门的用户移到部门 5
+	expectDeptScope()
+	mock.ExpectQuery("SELECT \\* FROM `users`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "dept_id"}).AddRow(7, "bob", 3))
+	mock.ExpectQuery("SELECT \\* FROM `departments`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(5, "财务部"))
+	expectDeptScope()
+
+	deptId := uint(5)
+	err := s.Update(ctx, 7, types.UpdateUserReq{DeptID: &deptId})
+	var e shared.Error
+	require.ErrorAs(t, err, &e)
+	assert.Equal(t, shared.ErrNoPermission.Code, e.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,113 @@
+package user
+
+import (
+	"context"
+	"go-tpl/infra/logger"
+	"go-tpl/logic/shared"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// roleSweepInterval 清理过期角色授予的间隔
+const roleSweepInterval = time.Minute
+
+// ActiveAt 判断角色授予在 t 时刻是否生效
+func (ur UserRole) ActiveAt(t time.Time) bool {
+	if ur.StartsAt != nil && ur.StartsAt.After(t) {
+		return false
+	}
+	return ur.ExpiresAt == nil || ur.ExpiresAt.After(t)
+}
+
+// ActiveRoles 查询条件：仅保留当前生效的用户角色（已到生效时间且未过期）
+func ActiveRoles(db *gorm.DB) *gorm.DB {
+	now := time.Now()
+	return db.Where("(user_roles.starts_at IS NULL OR user_roles.starts_at <= ?) AND (user_roles.expires_at IS NULL OR user_roles.expires_at > ?)", now, now)
+}
+
+// validateRoles 校验角色均存在且为启用状态，否则返回不合法的角色 ID
+func (s *Service) validateRoles(ctx context.Context, roleIds []uint) error {
+	if len(roleIds) == 0 {
+		return nil
+	}
+
+	var found []uint
+	err := s.db.WithContext(ctx).Table("roles").
+		Where("id IN ? AND status = ? AND deleted_at IS NULL", roleIds, shared.StatusActive).
+		Pluck("id", &found).Error
+	if err != nil {
+		return err
+	}
+	if invalid := shared.MissingIds(roleIds, found); len(invalid) > 0 {
+		return shared.ErrRoleInvalid.WithDetail(shared.JoinIds(invalid))
+	}
+	return nil
+}
+
+// lockUserRoles 锁定用户记录并读取其现有角色，串行化同一用户的并发角色变更
+func lockUserRoles(tx *gorm.DB, userId uint) ([]UserRole, error) {
+	var locked User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&locked, userId).Error; err != nil {
+		return nil, err
+	}
+
+	var existing []UserRole
+	if err := tx.Where("user_id = ?", userId).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// SweepExpiredRoles 删除已过期的角色授予，逐条记录日志，返回删除数量
+func (s *Service) SweepExpiredRoles(ctx context.Context) (int, error) {
+	now := time.Now()
+	var expired []UserRole
+	if err := s.db.WithContext(ctx).Where("expires_at <= ?", now).Find(&expired).Error; err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, ur := range expired {
+		// 多实例同时清理时，仅实际删除的实例记录日志
+		result := s.db.WithContext(ctx).
+			Where("user_id = ? AND role_id = ? AND expires_at <= ?", ur.UserID, ur.RoleID, now).
+			Delete(&UserRole{})
+		if result.Error != nil {
+			return removed, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		removed++
+
+		logger.Info(ctx, "expired role grant removed",
+			logger.Int("user_id", int(ur.UserID)),
+			logger.Int("role_id", int(ur.RoleID)),
+			logger.Str("expires_at", ur.ExpiresAt.Format(time.RFC3339)),
+			logger.Str("reason", ur.Reason))
+
+		if err := shared.InvalidateUserPermissions(ctx, s.redis, ur.UserID); err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+// RunRoleSweeper 定期清理过期的角色授予，直到 ctx 取消
+func (s *Service) RunRoleSweeper(ctx context.Context) {
+	ticker := time.NewTicker(roleSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.SweepExpiredRoles(ctx); err != nil {
+				logger.Errw(ctx, err)
+			}
+		}
+	}
+}
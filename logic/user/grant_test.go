@@ -0,0 +1,41 @@
+package user
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserRoleActiveAt(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		v := now.Add(d)
+		return &v
+	}
+
+	cases := []struct {
+		name      string
+		startsAt  *time.Time
+		expiresAt *time.Time
+		want      bool
+	}{
+		{"permanent", nil, nil, true},
+		{"started", at(-time.Hour), nil, true},
+		{"starts now", at(0), nil, true},
+		{"not started", at(time.Nanosecond), nil, false},
+		{"not expired", nil, at(time.Nanosecond), true},
+		// 过期时间为开区间，到达过期时间即失效
+		{"expires now", nil, at(0), false},
+		{"expired", nil, at(-time.Hour), false},
+		{"within window", at(-time.Hour), at(time.Hour), true},
+		{"before window", at(time.Hour), at(2 * time.Hour), false},
+		{"after window", at(-2 * time.Hour), at(-time.Hour), false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ur := UserRole{StartsAt: c.startsAt, ExpiresAt: c.expiresAt}
+			assert.Equal(t, c.want, ur.ActiveAt(now))
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"go-tpl/logic/shared"
+	"go-tpl/web/types"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	maxUsernameLength   = 50
+	provisionMaxRetries = 5
+)
+
+// GetByIdentity 根据外部身份获取绑定的用户
+func (s *Service) GetByIdentity(ctx context.Context, provider, subject string) (*User, error) {
+	var identity Identity
+	if err := s.db.WithContext(ctx).Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, shared.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return s.Get(ctx, identity.UserID)
+}
+
+// LinkIdentity 将外部身份绑定到用户
+func (s *Service) LinkIdentity(ctx context.Context, userID uint, provider, subject, email string) error {
+	identity := Identity{
+		UserID:   userID,
+		Provider: provider,
+		Subject:  subject,
+		Email:    email,
+	}
+	return s.db.WithContext(ctx).Create(&identity).Error
+}
+
+// ProvisionExternalUser 为外部身份自动创建用户，设置随机密码，仅能通过外部身份或重置密码登录
+func (s *Service) ProvisionExternalUser(ctx context.Context, email, preferredUsername string) (*User, error) {
+	base := preferredUsername
+	if base == "" {
+		base, _, _ = strings.Cut(email, "@")
+	}
+	// 预留随机后缀的长度
+	base = shared.Truncate(base, maxUsernameLength-5)
+
+	password, err := shared.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	// 用户名冲突时追加随机后缀
+	username := base
+	for range provisionMaxRetries {
+		user, err := s.createUser(ctx, types.CreateUserReq{
+			Username: username,
+			Email:    email,
+			Password: password,
+		}, shared.StatusActive)
+		if !errors.Is(err, shared.ErrUserExists) {
+			return user, err
+		}
+
+		suffix := make([]byte, 2)
+		if _, err = rand.Read(suffix); err != nil {
+			return nil, err
+		}
+		username = base + "_" + hex.EncodeToString(suffix)
+	}
+	return nil, shared.ErrUserExists
+}
@@ -0,0 +1,123 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"go-tpl/infra"
+	"go-tpl/logic/shared"
+	"time"
+)
+
+// loginLimits 获取登录限制配置
+func loginLimits() (maxAttempts, maxIPAttempts int64, lockDuration time.Duration) {
+	cfg := infra.Cfg.Login
+	maxAttempts, maxIPAttempts, lockDuration = cfg.MaxAttempts, cfg.MaxIPAttempts, time.Duration(cfg.LockDuration)*time.Second
+	if maxAttempts <= 0 {
+		maxAttempts = 5
+	}
+	if maxIPAttempts <= 0 {
+		maxIPAttempts = 20
+	}
+	if lockDuration <= 0 {
+		lockDuration = 15 * time.Minute
+	}
+	return
+}
+
+// failureDelay 根据失败次数计算递增的响应延迟
+func failureDelay(failures int64) time.Duration {
+	step, maxDelay := infra.Cfg.Login.DelayStep, infra.Cfg.Login.MaxDelay
+	if step <= 0 {
+		step = 200
+	}
+	if maxDelay <= 0 {
+		maxDelay = 3000
+	}
+	return time.Duration(min(failures*step, maxDelay)) * time.Millisecond
+}
+
+// checkLoginLock 检查用户名或 IP 是否处于锁定状态
+func (s *Service) checkLoginLock(ctx context.Context, username, ip string) error {
+	keys := []string{fmt.Sprintf(shared.CacheLoginLockUser, username)}
+	if ip != "" {
+		keys = append(keys, fmt.Sprintf(shared.CacheLoginLockIP, ip))
+	}
+
+	n, err := s.redis.Exists(ctx, keys...).Result()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return shared.ErrAccountLocked
+	}
+	return nil
+}
+
+// recordLoginFailure 记录登录失败，达到阈值后锁定，并按失败次数延迟响应
+func (s *Service) recordLoginFailure(ctx context.Context, username, ip string) error {
+	maxAttempts, maxIPAttempts, lockDuration := loginLimits()
+
+	failures, err := s.incrFailure(ctx, fmt.Sprintf(shared.CacheLoginFailUser, username),
+		fmt.Sprintf(shared.CacheLoginLockUser, username), maxAttempts, lockDuration)
+	if err != nil {
+		return err
+	}
+
+	if ip != "" {
+		if _, err = s.incrFailure(ctx, fmt.Sprintf(shared.CacheLoginFailIP, ip),
+			fmt.Sprintf(shared.CacheLoginLockIP, ip), maxIPAttempts, lockDuration); err != nil {
+			return err
+		}
+	}
+
+	// 递增延迟，拖慢暴力破解
+	select {
+	case <-ctx.Done():
+	case <-time.After(failureDelay(failures)):
+	}
+	return nil
+}
+
+// incrFailure 递增失败计数，达到上限时设置锁定并重置计数
+func (s *Service) incrFailure(ctx context.Context, failKey, lockKey string, limit int64, lockDuration time.Duration) (int64, error) {
+	failures, err := shared.IncrWithTTL(ctx, s.redis, failKey, lockDuration)
+	if err != nil {
+		return 0, err
+	}
+
+	if failures >= limit {
+		if err = s.redis.Set(ctx, lockKey, 1, lockDuration).Err(); err != nil {
+			return 0, err
+		}
+		if err = s.redis.Del(ctx, failKey).Err(); err != nil {
+			return 0, err
+		}
+	}
+	return failures, nil
+}
+
+// clearLoginFailures 登录成功后清除用户名的失败计数
+func (s *Service) clearLoginFailures(ctx context.Context, username string) error {
+	return s.redis.Del(ctx, fmt.Sprintf(shared.CacheLoginFailUser, username)).Err()
+}
+
+// Unlock 手动解锁用户账户，仅清除用户名的失败计数和锁定；IP 锁定与账户无关，通过 UnlockIP 解除
+func (s *Service) Unlock(ctx context.Context, id uint) error {
+	user, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return s.redis.Del(ctx,
+		fmt.Sprintf(shared.CacheLoginFailUser, user.Username),
+		fmt.Sprintf(shared.CacheLoginLockUser, user.Username),
+	).Err()
+}
+
+// UnlockIP 手动解除 IP 的登录失败计数和锁定
+func (s *Service) UnlockIP(ctx context.Context, ip string) error {
+	return s.redis.Del(ctx,
+		fmt.Sprintf(shared.CacheLoginFailIP, ip),
+		fmt.Sprintf(shared.CacheLoginLockIP, ip),
+	).Err()
+}
@@ -0,0 +1,113 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"go-tpl/infra"
+	"go-tpl/infra/config"
+	"go-tpl/logic/shared"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setLoginConfig(t *testing.T, cfg config.LoginConfig) {
+	prev := infra.Cfg
+	infra.Cfg = &config.Config{Login: cfg}
+	t.Cleanup(func() { infra.Cfg = prev })
+}
+
+func TestLoginLimits(t *testing.T) {
+	setLoginConfig(t, config.LoginConfig{})
+	maxAttempts, maxIPAttempts, lockDuration := loginLimits()
+	assert.Equal(t, int64(5), maxAttempts)
+	assert.Equal(t, int64(20), maxIPAttempts)
+	assert.Equal(t, 15*time.Minute, lockDuration)
+
+	assert.Equal(t, 200*time.Millisecond, failureDelay(1))
+	assert.Equal(t, 3*time.Second, failureDelay(100))
+}
+
+func TestLoginLockout(t *testing.T) {
+	ctx := context.Background()
+	setLoginConfig(t, config.LoginConfig{MaxAttempts: 3, MaxIPAttempts: 5, LockDuration: 60, DelayStep: 1, MaxDelay: 1})
+
+	setup := func(t *testing.T) (*Service, *miniredis.Miniredis) {
+		mr := miniredis.RunT(t)
+		return &Service{redis: redis.NewClient(&redis.Options{Addr: mr.Addr()})}, mr
+	}
+	fail := func(t *testing.T, s *Service, username, ip string, times int) {
+		for range times {
+			require.NoError(t, s.recordLoginFailure(ctx, username, ip))
+		}
+	}
+
+	t.Run("Username", func(t *testing.T) {
+		s, mr := setup(t)
+		fail(t, s, "alice", "", 2)
+		assert.NoError(t, s.checkLoginLock(ctx, "alice", "10.0.0.1"))
+
+		// 达到上限后锁定并重置计数，换 IP 同样被拒绝
+		fail(t, s, "alice", "", 1)
+		assert.ErrorIs(t, s.checkLoginLock(ctx, "alice", "10.0.0.2"), shared.ErrAccountLocked)
+		assert.Equal(t, time.Minute, mr.TTL(fmt.Sprintf(shared.CacheLoginLockUser, "alice")))
+		assert.False(t, mr.Exists(fmt.Sprintf(shared.CacheLoginFailUser, "alice")))
+		assert.NoError(t, s.checkLoginLock(ctx, "bob", "10.0.0.2"))
+
+		// 登录成功清除失败计数
+		fail(t, s, "bob", "", 2)
+		require.NoError(t, s.clearLoginFailures(ctx, "bob"))
+		fail(t, s, "bob", "", 2)
+		assert.NoError(t, s.checkLoginLock(ctx, "bob", ""))
+
+		// 锁定到期后解除
+		mr.FastForward(time.Minute)
+		assert.NoError(t, s.checkLoginLock(ctx, "alice", ""))
+	})
+
+	t.Run("IP", func(t *testing.T) {
+		s, _ := setup(t)
+		// 不同用户名的失败计入同一 IP
+		for i := range 4 {
+			fail(t, s, fmt.Sprintf("user%d", i), "10.0.0.1", 1)
+		}
+		assert.NoError(t, s.checkLoginLock(ctx, "carol", "10.0.0.1"))
+
+		fail(t, s, "user4", "10.0.0.1", 1)
+		assert.ErrorIs(t, s.checkLoginLock(ctx, "carol", "10.0.0.1"), shared.ErrAccountLocked)
+		assert.NoError(t, s.checkLoginLock(ctx, "carol", "10.0.0.2"))
+		assert.NoError(t, s.checkLoginLock(ctx, "carol", ""))
+	})
+
+	t.Run("Unlock", func(t *testing.T) {
+		s, _ := setup(t)
+		gormDB, mock := setupTestDB(t)
+		s.db = gormDB
+
+		fail(t, s, "alice", "10.0.0.1", 3)
+		fail(t, s, "bob", "10.0.0.1", 2)
+		require.ErrorIs(t, s.checkLoginLock(ctx, "alice", ""), shared.ErrAccountLocked)
+		require.ErrorIs(t, s.checkLoginLock(ctx, "carol", "10.0.0.1"), shared.ErrAccountLocked)
+
+		// 解锁账户不解除 IP 锁定
+		mock.ExpectQuery("SELECT \\* FROM `users`").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(7, "alice"))
+		require.NoError(t, s.Unlock(ctx, 7))
+		require.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, s.checkLoginLock(ctx, "alice", ""))
+		assert.ErrorIs(t, s.checkLoginLock(ctx, "alice", "10.0.0.1"), shared.ErrAccountLocked)
+
+		require.NoError(t, s.UnlockIP(ctx, "10.0.0.1"))
+		assert.NoError(t, s.checkLoginLock(ctx, "alice", "10.0.0.1"))
+
+		// 解除后重新计数
+		fail(t, s, "dave", "10.0.0.1", 2)
+		fail(t, s, "erin", "10.0.0.1", 2)
+		assert.NoError(t, s.checkLoginLock(ctx, "frank", "10.0.0.1"))
+	})
+}
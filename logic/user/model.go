@@ -11,20 +11,86 @@ type User struct {
 	Username  string         `gorm:"uniqueIndex;size:50;not null" json:"username"`
 	Email     string         `gorm:"size:100;not null" json:"email"`
 	Password  string         `gorm:"size:255;not null" json:"-"`
-	Status    int8           `gorm:"default:1" json:"status"` // 1-正常, 0-禁用
+	Status    int8           `gorm:"default:1" json:"status"`        // 1-正常, 0-禁用, 2-待验证
+	DeptID    uint           `gorm:"index;default:0" json:"dept_id"` // 所属部门，0 表示未分配
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	TwoFactorEnabled bool   `gorm:"default:false" json:"two_factor_enabled"`
+	TwoFactorSecret  string `gorm:"size:64" json:"-"`
+
+	PendingEmail string `gorm:"size:100;not null;default:''" json:"pending_email,omitempty"` // 自行修改后尚未验证的新邮箱，验证通过后替换 Email
 }
 
 func (User) TableName() string {
 	return "users"
 }
 
-// UserRole 用户角色关联表
+// RecoveryCode 两步验证恢复码（仅保存哈希）
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	CodeHash  string     `gorm:"size:64;not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (RecoveryCode) TableName() string {
+	return "user_recovery_codes"
+}
+
+// PasswordHistory 历史密码哈希，用于禁止重复使用最近的密码
+type PasswordHistory struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	UserID       uint      `gorm:"index;not null" json:"user_id"`
+	PasswordHash string    `gorm:"size:255;not null" json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (PasswordHistory) TableName() string {
+	return "user_password_histories"
+}
+
+// Identity 外部身份提供方账户绑定
+type Identity struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"index;not null" json:"user_id"`
+	Provider  string    `gorm:"uniqueIndex:uk_provider_subject;size:50;not null" json:"provider"`
+	Subject   string    `gorm:"uniqueIndex:uk_provider_subject;size:255;not null" json:"subject"`
+	Email     string    `gorm:"size:100" json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (Identity) TableName() string {
+	return "user_identities"
+}
+
+// Session 登录会话，对应一个 refresh token 家族
+type Session struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"index;not null" json:"user_id"`
+	FamilyID   string     `gorm:"uniqueIndex;size:32;not null" json:"-"`
+	UserAgent  string     `gorm:"size:255" json:"user_agent"`
+	IP         string     `gorm:"size:45" json:"ip"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+
+	Current bool `gorm:"-" json:"current"` // 是否为当前请求所属会话
+}
+
+func (Session) TableName() string {
+	return "user_sessions"
+}
+
+// UserRole 用户角色关联表，可设置生效时间和过期时间
 type UserRole struct {
-	UserID uint `gorm:"column:user_id" json:"user_id"`
-	RoleID uint `gorm:"column:role_id" json:"role_id"`
+	UserID    uint       `gorm:"column:user_id" json:"user_id"`
+	RoleID    uint       `gorm:"column:role_id" json:"role_id"`
+	StartsAt  *time.Time `json:"starts_at"`               // 为空表示立即生效
+	ExpiresAt *time.Time `gorm:"index" json:"expires_at"` // 为空表示永不过期
+	Reason    string     `gorm:"size:255" json:"reason"`  // 授予原因
 }
 
 func (UserRole) TableName() string {
@@ -0,0 +1,138 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"go-tpl/infra/logger"
+	pwd "go-tpl/infra/password"
+	"go-tpl/logic/shared"
+
+	"gorm.io/gorm"
+)
+
+// validatePassword 按密码策略校验新密码，返回对应规则的错误
+func (s *Service) validatePassword(password string, userInfo ...string) error {
+	err := s.policy.Validate(password, userInfo...)
+	if err == nil {
+		return nil
+	}
+
+	var v *pwd.Violation
+	if !errors.As(err, &v) {
+		return err
+	}
+	switch v.Rule {
+	case pwd.RuleMinLength:
+		return shared.ErrPasswordTooShort.WithDetail(v.Detail)
+	case pwd.RuleMaxLength:
+		return shared.ErrPasswordTooLong.WithDetail(v.Detail)
+	case pwd.RuleCharClass:
+		return shared.ErrPasswordWeak.WithDetail(v.Detail)
+	case pwd.RuleUserInfo:
+		return shared.ErrPasswordUserInfo
+	case pwd.RuleBanned:
+		return shared.ErrPasswordBanned
+	default:
+		return shared.ErrInvalidPassword
+	}
+}
+
+// ValidateNewPassword 校验用户的新密码是否符合密码策略且未被近期使用，
+// 用于在消耗一次性凭证（如密码重置 token）之前预先校验，避免凭证因密码不合规而作废
+func (s *Service) ValidateNewPassword(ctx context.Context, id uint, password string) error {
+	user, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err = s.validatePassword(password, user.Username, user.Email); err != nil {
+		return err
+	}
+	return s.checkPasswordReuse(ctx, user, password)
+}
+
+// checkPasswordReuse 检查新密码是否与当前密码或最近使用过的密码相同
+func (s *Service) checkPasswordReuse(ctx context.Context, user *User, password string) error {
+	size := s.policy.HistorySize()
+	if size <= 0 {
+		return nil
+	}
+
+	hashes := []string{user.Password}
+	var history []string
+	if err := s.db.WithContext(ctx).Model(&PasswordHistory{}).
+		Where("user_id = ?", user.ID).
+		Order("id DESC").
+		Limit(size).
+		Pluck("password_hash", &history).Error; err != nil {
+		return err
+	}
+	hashes = append(hashes, history...)
+
+	for _, hash := range hashes {
+		if ok, _, _ := s.hasher.Verify(password, hash); ok {
+			return shared.ErrPasswordReused
+		}
+	}
+	return nil
+}
+
+// rehashPassword 登录成功后升级密码哈希，失败不影响登录
+// 仅在哈希未被并发修改时更新，历史记录中的旧哈希保持不变
+func (s *Service) rehashPassword(ctx context.Context, user *User, password string) {
+	hashedPassword, err := s.hasher.Hash(password)
+	if err != nil {
+		logger.Errw(ctx, err, logger.Int("user_id", int(user.ID)))
+		return
+	}
+
+	err = s.db.WithContext(ctx).Model(&User{}).
+		Where("id = ? AND password = ?", user.ID, user.Password).
+		Update("password", hashedPassword).Error
+	if err != nil {
+		logger.Errw(ctx, err, logger.Int("user_id", int(user.ID)))
+		return
+	}
+	user.Password = hashedPassword
+	logger.Info(ctx, "password hash upgraded", logger.Int("user_id", int(user.ID)))
+}
+
+// recordPasswordHistory 记录密码哈希，只保留最近 N 条
+func (s *Service) recordPasswordHistory(tx *gorm.DB, userID uint, hash string) error {
+	size := s.policy.HistorySize()
+	if size <= 0 {
+		return nil
+	}
+
+	if err := tx.Create(&PasswordHistory{UserID: userID, PasswordHash: hash}).Error; err != nil {
+		return err
+	}
+
+	// 删除超出保留数量的旧记录
+	var keepIDs []uint
+	if err := tx.Model(&PasswordHistory{}).
+		Where("user_id = ?", userID).
+		Order("id DESC").
+		Limit(size).
+		Pluck("id", &keepIDs).Error; err != nil {
+		return err
+	}
+	return tx.Where("user_id = ? AND id NOT IN ?", userID, keepIDs).Delete(&PasswordHistory{}).Error
+}
+
+// ResetRandomPassword 将密码重置为随机值并吊销已签发的 token，不校验密码策略
+// 用于使他人可能知晓的本地密码失效
+func (s *Service) ResetRandomPassword(ctx context.Context, id uint) error {
+	password, err := shared.RandomToken(32)
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := s.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	if err = s.db.WithContext(ctx).Model(&User{}).Where("id = ?", id).Update("password", hashedPassword).Error; err != nil {
+		return err
+	}
+	return s.RevokeAllSessions(ctx, id)
+}
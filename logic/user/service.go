@@ -4,24 +4,32 @@ import (
 	"context"
 	"errors"
 	"go-tpl/infra/logger"
+	pwd "go-tpl/infra/password"
+	"go-tpl/logic/dept"
 	"go-tpl/logic/shared"
 	"go-tpl/web/types"
-
-	"golang.org/x/crypto/bcrypt"
+	"slices"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 )
 
 type Service struct {
-	db    *gorm.DB
-	redis *redis.Client
+	db      *gorm.DB
+	redis   *redis.Client
+	policy  *pwd.Policy
+	hasher  *pwd.Hasher
+	deptSvc *dept.Service
 }
 
-func NewService(db *gorm.DB, redis *redis.Client) *Service {
+func NewService(db *gorm.DB, redis *redis.Client, policy *pwd.Policy, hasher *pwd.Hasher, deptSvc *dept.Service) *Service {
 	return &Service{
-		db:    db,
-		redis: redis,
+		db:      db,
+		redis:   redis,
+		policy:  policy,
+		hasher:  hasher,
+		deptSvc: deptSvc,
 	}
 }
 
@@ -35,7 +43,12 @@ func (s *Service) List(ctx context.Context, req types.UserQueryReq) (*shared.Pag
 	get := s.redis.Get(ctx, "user_list")
 	logger.Info(ctx, "get rdb: "+get.String())
 
-	_db := s.db.WithContext(ctx).Model(&User{})
+	scope, err := s.dataScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_db := s.db.WithContext(ctx).Model(&User{}).Scopes(scope)
 	if req.Username != "" {
 		_db = _db.Where("username like ?", "%"+req.Username+"%")
 	}
@@ -59,8 +72,25 @@ func (s *Service) List(ctx context.Context, req types.UserQueryReq) (*shared.Pag
 
 // Get 获取单个用户
 func (s *Service) Get(ctx context.Context, id uint) (*User, error) {
+	scope, err := s.dataScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var user User
+	if err = s.db.WithContext(ctx).Scopes(scope).First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, shared.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetByEmail 根据邮箱获取用户
+func (s *Service) GetByEmail(ctx context.Context, email string) (*User, error) {
 	var user User
-	if err := s.db.WithContext(ctx).First(&user, id).Error; err != nil {
+	if err := s.db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, shared.ErrRecordNotFound
 		}
@@ -71,6 +101,23 @@ func (s *Service) Get(ctx context.Context, id uint) (*User, error) {
 
 // Create 创建用户
 func (s *Service) Create(ctx context.Context, req types.CreateUserReq) (*User, error) {
+	return s.create(ctx, req, shared.StatusActive)
+}
+
+// CreatePending 创建待验证邮箱的用户
+func (s *Service) CreatePending(ctx context.Context, req types.CreateUserReq) (*User, error) {
+	return s.create(ctx, req, shared.StatusPending)
+}
+
+func (s *Service) create(ctx context.Context, req types.CreateUserReq, status int8) (*User, error) {
+	if err := s.validatePassword(req.Password, req.Username, req.Email); err != nil {
+		return nil, err
+	}
+	return s.createUser(ctx, req, status)
+}
+
+// createUser 创建用户，不校验密码策略
+func (s *Service) createUser(ctx context.Context, req types.CreateUserReq, status int8) (*User, error) {
 	// 检查用户名是否已存在
 	var count int64
 	if err := s.db.WithContext(ctx).Model(&User{}).Where("username = ?", req.Username).Count(&count).Error; err != nil {
@@ -88,8 +135,17 @@ func (s *Service) Create(ctx context.Context, req types.CreateUserReq) (*User, e
 		return nil, shared.ErrEmailExists
 	}
 
+	if req.DeptID != 0 {
+		if _, err := s.deptSvc.Get(ctx, req.DeptID); err != nil {
+			return nil, err
+		}
+		if err := s.checkDeptInScope(ctx, req.DeptID); err != nil {
+			return nil, err
+		}
+	}
+
 	// 加密密码
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := s.hasher.Hash(req.Password)
 	if err != nil {
 		return nil, shared.ErrInvalidPassword
 	}
@@ -97,11 +153,18 @@ func (s *Service) Create(ctx context.Context, req types.CreateUserReq) (*User, e
 	user := User{
 		Username: req.Username,
 		Email:    req.Email,
-		Password: string(hashedPassword),
-		Status:   shared.StatusActive,
+		Password: hashedPassword,
+		Status:   status,
+		DeptID:   req.DeptID,
 	}
 
-	if err := s.db.WithContext(ctx).Create(&user).Error; err != nil {
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return s.recordPasswordHistory(tx, user.ID, user.Password)
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -141,22 +204,137 @@ func (s *Service) Update(ctx context.Context, id uint, req types.UpdateUserReq)
 	}
 
 	if req.Password != "" {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		username, email := user.Username, user.Email
+		if req.Username != "" {
+			username = req.Username
+		}
+		if req.Email != "" {
+			email = req.Email
+		}
+		if err = s.validatePassword(req.Password, username, email); err != nil {
+			return err
+		}
+		if err = s.checkPasswordReuse(ctx, user, req.Password); err != nil {
+			return err
+		}
+
+		hashedPassword, err := s.hasher.Hash(req.Password)
 		if err != nil {
 			return shared.ErrInvalidPassword
 		}
-		updates["password"] = string(hashedPassword)
+		updates["password"] = hashedPassword
 	}
 
 	if req.Status != nil {
 		updates["status"] = *req.Status
 	}
 
+	if req.DeptID != nil {
+		// 部门决定数据范围，不能修改自己的部门；新部门须在调用者的数据范围内
+		if *req.DeptID != user.DeptID {
+			if callerID, ok := shared.CallerFromContext(ctx); ok && callerID == id {
+				return shared.ErrNoPermission.WithDetail("cannot change own department")
+			}
+			if *req.DeptID != 0 {
+				if _, err = s.deptSvc.Get(ctx, *req.DeptID); err != nil {
+					return err
+				}
+				if err = s.checkDeptInScope(ctx, *req.DeptID); err != nil {
+					return err
+				}
+			}
+		}
+		updates["dept_id"] = *req.DeptID
+	}
+
 	if len(updates) == 0 {
 		return shared.ErrInvalidParam
 	}
 
-	return s.db.WithContext(ctx).Model(&User{}).Where("id = ?", id).Updates(updates).Error
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&User{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+			return err
+		}
+		if hashedPassword, ok := updates["password"].(string); ok {
+			return s.recordPasswordHistory(tx, id, hashedPassword)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// 修改密码或禁用用户后，吊销其已签发的 token
+	if req.Password != "" || (req.Status != nil && *req.Status != shared.StatusActive) {
+		return s.RevokeAllSessions(ctx, id)
+	}
+	return nil
+}
+
+// UpdateProfile 用户更新自己的资料，返回更新后的用户。
+// 新邮箱不直接生效，记为待验证邮箱，用户通过发送到新邮箱的链接完成验证后才替换原邮箱；
+// 提交原邮箱表示取消待验证的修改
+func (s *Service) UpdateProfile(ctx context.Context, id uint, req types.UpdateProfileReq) (*User, error) {
+	user, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	changeUsername := req.Username != "" && req.Username != user.Username
+	if !changeUsername && req.Email == "" {
+		return nil, shared.ErrInvalidParam
+	}
+
+	if changeUsername {
+		if err = s.Update(ctx, id, types.UpdateUserReq{Username: req.Username}); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Email != "" {
+		pendingEmail := req.Email
+		if req.Email == user.Email {
+			pendingEmail = ""
+		} else {
+			var count int64
+			if err = s.db.WithContext(ctx).Model(&User{}).Where("email = ? AND id != ?", req.Email, id).Count(&count).Error; err != nil {
+				return nil, err
+			}
+			if count > 0 {
+				return nil, shared.ErrEmailExists
+			}
+		}
+		if err = s.db.WithContext(ctx).Model(&User{}).Where("id = ?", id).Update("pending_email", pendingEmail).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return s.Get(ctx, id)
+}
+
+// ChangePassword 校验原密码后修改密码，已签发的 token 全部失效
+func (s *Service) ChangePassword(ctx context.Context, id uint, req types.ChangePasswordReq) error {
+	user, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// 原密码校验失败同样计入登录失败次数，防止盗用 token 后暴力猜测密码
+	if err = s.checkLoginLock(ctx, user.Username, ""); err != nil {
+		return err
+	}
+	ok, _, err := s.hasher.Verify(req.OldPassword, user.Password)
+	if err != nil {
+		logger.Errw(ctx, err, logger.Int("user_id", int(id)))
+	}
+	if !ok {
+		if err = s.recordLoginFailure(ctx, user.Username, ""); err != nil {
+			logger.Errw(ctx, err)
+		}
+		return shared.ErrOldPasswordWrong
+	}
+
+	return s.Update(ctx, id, types.UpdateUserReq{Password: req.NewPassword})
 }
 
 // Delete 删除用户
@@ -167,7 +345,15 @@ func (s *Service) Delete(ctx context.Context, id uint) error {
 		return err
 	}
 
-	return s.db.WithContext(ctx).Delete(&User{}, id).Error
+	if err = s.db.WithContext(ctx).Delete(&User{}, id).Error; err != nil {
+		return err
+	}
+	if err = shared.InvalidateUserPermissions(ctx, s.redis, id); err != nil {
+		return err
+	}
+
+	// 吊销已删除用户的 token
+	return s.RevokeAllSessions(ctx, id)
 }
 
 // UpdateStatus 更新用户状态
@@ -177,14 +363,76 @@ func (s *Service) UpdateStatus(ctx context.Context, id uint, status int8) error
 		return err
 	}
 
-	return s.db.WithContext(ctx).Model(&User{}).Where("id = ?", id).Update("status", status).Error
+	if err = s.db.WithContext(ctx).Model(&User{}).Where("id = ?", id).Update("status", status).Error; err != nil {
+		return err
+	}
+
+	// 禁用用户后，吊销其已签发的 token
+	if status != shared.StatusActive {
+		return s.RevokeAllSessions(ctx, id)
+	}
+	return nil
 }
 
-// GetUserRoles 获取用户角色列表
-func (s *Service) GetUserRoles(ctx context.Context, userId uint) ([]uint, error) {
-	var userRoles []UserRole
-	err := s.db.WithContext(ctx).Where("user_id = ?", userId).Find(&userRoles).Error
+// VerifyEmail 验证邮箱并激活待验证用户；email 为待验证的新邮箱时确认邮箱修改
+func (s *Service) VerifyEmail(ctx context.Context, id uint, email string) error {
+	user, err := s.Get(ctx, id)
 	if err != nil {
+		return err
+	}
+
+	if user.PendingEmail != "" && user.PendingEmail == email {
+		return s.confirmEmailChange(ctx, user)
+	}
+
+	// 邮箱已变更的旧链接失效
+	if user.Email != email {
+		return shared.ErrVerifyTokenInvalid
+	}
+	if user.Status == shared.StatusActive {
+		return nil
+	}
+	if user.Status != shared.StatusPending {
+		return shared.ErrUserDisabled
+	}
+
+	return s.db.WithContext(ctx).Model(&User{}).
+		Where("id = ? AND status = ?", id, shared.StatusPending).
+		Update("status", shared.StatusActive).Error
+}
+
+// confirmEmailChange 使用已验证的待验证邮箱替换原邮箱，新邮箱在验证期间被其他账户占用时失败
+func (s *Service) confirmEmailChange(ctx context.Context, user *User) error {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&User{}).Where("email = ? AND id != ?", user.PendingEmail, user.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return shared.ErrEmailExists
+	}
+
+	updates := map[string]interface{}{
+		"email":         user.PendingEmail,
+		"pending_email": "",
+	}
+	// 待验证的新用户同时完成激活
+	if user.Status == shared.StatusPending {
+		updates["status"] = shared.StatusActive
+	}
+	return s.db.WithContext(ctx).Model(&User{}).
+		Where("id = ? AND pending_email = ?", user.ID, user.PendingEmail).
+		Updates(updates).Error
+}
+
+// GetUserRoles 获取用户角色列表，默认仅包含当前生效的角色，includeInactive 为 true 时包含未生效和已过期的角色
+func (s *Service) GetUserRoles(ctx context.Context, userId uint, includeInactive bool) ([]uint, error) {
+	_db := s.db.WithContext(ctx).Where("user_id = ?", userId)
+	if !includeInactive {
+		_db = _db.Scopes(ActiveRoles)
+	}
+
+	var userRoles []UserRole
+	if err := _db.Find(&userRoles).Error; err != nil {
 		return nil, err
 	}
 
@@ -195,58 +443,231 @@ func (s *Service) GetUserRoles(ctx context.Context, userId uint) ([]uint, error)
 	return roleIds, nil
 }
 
-// AssignRoles 为用户分配角色
-func (s *Service) AssignRoles(ctx context.Context, userId uint, roleIds []uint) error {
+// AssignRoles 为用户分配角色，覆盖现有角色；grants 中的角色可设置生效时间、过期时间和授予原因。
+// 角色须存在且为启用状态，仅增删有差异的角色，已有角色更新其有效期和授予原因
+func (s *Service) AssignRoles(ctx context.Context, userId uint, req types.AssignUserRolesReq) (*shared.AssignResult, error) {
+	if req.RoleIds == nil && req.Grants == nil {
+		return nil, shared.ErrInvalidParam
+	}
+
 	// 检查用户是否存在
 	_, err := s.Get(ctx, userId)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	userRoles, err := buildUserRoles(userId, req, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	roleIds := make([]uint, len(userRoles))
+	for i, ur := range userRoles {
+		roleIds[i] = ur.RoleID
+	}
+	if err = s.validateRoles(ctx, roleIds); err != nil {
+		return nil, err
 	}
 
-	// 开启事务
-	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// 删除用户现有角色
-		if err := tx.Where("user_id = ?", userId).Delete(&UserRole{}).Error; err != nil {
+	result := shared.NewAssignResult()
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		existing, err := lockUserRoles(tx, userId)
+		if err != nil {
 			return err
 		}
 
-		// 分配新角色
-		if len(roleIds) > 0 {
-			userRoles := make([]UserRole, len(roleIds))
-			for i, roleId := range roleIds {
-				userRoles[i] = UserRole{
-					UserID: userId,
-					RoleID: roleId,
-				}
-			}
-			if err := tx.Create(&userRoles).Error; err != nil {
+		currentIds := make([]uint, len(existing))
+		for i, ur := range existing {
+			currentIds[i] = ur.RoleID
+		}
+		result.Added, result.Removed = shared.DiffIds(currentIds, roleIds)
+		if len(result.Removed) > 0 {
+			if err = tx.Where("user_id = ? AND role_id IN ?", userId, result.Removed).Delete(&UserRole{}).Error; err != nil {
 				return err
 			}
 		}
 
+		added := make([]UserRole, 0)
+		for _, ur := range userRoles {
+			if slices.Contains(result.Added, ur.RoleID) {
+				added = append(added, ur)
+				continue
+			}
+
+			// 已有的角色更新有效期和授予原因
+			err = tx.Model(&UserRole{}).Where("user_id = ? AND role_id = ?", userId, ur.RoleID).Updates(map[string]interface{}{
+				"starts_at":  ur.StartsAt,
+				"expires_at": ur.ExpiresAt,
+				"reason":     ur.Reason,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		if len(added) > 0 {
+			return tx.Create(&added).Error
+		}
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = shared.InvalidateUserPermissions(ctx, s.redis, userId); err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
-// ValidateLogin 验证用户登录
-func (s *Service) ValidateLogin(ctx context.Context, username, password string) (*User, error) {
+// AddRole 为用户增加单个角色，可设置有效期和授予原因；角色已分配时不做修改
+func (s *Service) AddRole(ctx context.Context, userId, roleId uint, req types.AddUserRoleReq) (*shared.AssignResult, error) {
+	if _, err := s.Get(ctx, userId); err != nil {
+		return nil, err
+	}
+
+	userRoles, err := buildUserRoles(userId, types.AssignUserRolesReq{
+		Grants: []types.RoleGrantReq{{RoleID: roleId, StartsAt: req.StartsAt, ExpiresAt: req.ExpiresAt, Reason: req.Reason}},
+	}, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err = s.validateRoles(ctx, []uint{roleId}); err != nil {
+		return nil, err
+	}
+
+	result := shared.NewAssignResult()
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		existing, err := lockUserRoles(tx, userId)
+		if err != nil {
+			return err
+		}
+		for _, ur := range existing {
+			if ur.RoleID == roleId {
+				return nil
+			}
+		}
+
+		result.Added = append(result.Added, roleId)
+		return tx.Create(&userRoles).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Changed() {
+		if err = shared.InvalidateUserPermissions(ctx, s.redis, userId); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// RemoveRole 移除用户的单个角色，角色未分配时不做修改
+func (s *Service) RemoveRole(ctx context.Context, userId, roleId uint) (*shared.AssignResult, error) {
+	if _, err := s.Get(ctx, userId); err != nil {
+		return nil, err
+	}
+
+	deleted := s.db.WithContext(ctx).Where("user_id = ? AND role_id = ?", userId, roleId).Delete(&UserRole{})
+	if deleted.Error != nil {
+		return nil, deleted.Error
+	}
+
+	result := shared.NewAssignResult()
+	if deleted.RowsAffected > 0 {
+		result.Removed = append(result.Removed, roleId)
+		if err := shared.InvalidateUserPermissions(ctx, s.redis, userId); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// buildUserRoles 将分配请求转换为用户角色，同一角色不能重复，过期时间须晚于生效时间和当前时间
+func buildUserRoles(userId uint, req types.AssignUserRolesReq, now time.Time) ([]UserRole, error) {
+	userRoles := make([]UserRole, 0, len(req.RoleIds)+len(req.Grants))
+	seen := make(map[uint]bool)
+	for _, roleId := range req.RoleIds {
+		if seen[roleId] {
+			return nil, shared.ErrInvalidParam
+		}
+		seen[roleId] = true
+		userRoles = append(userRoles, UserRole{UserID: userId, RoleID: roleId})
+	}
+
+	for _, grant := range req.Grants {
+		if seen[grant.RoleID] {
+			return nil, shared.ErrInvalidParam
+		}
+		seen[grant.RoleID] = true
+
+		if grant.ExpiresAt != nil {
+			if !grant.ExpiresAt.After(now) || (grant.StartsAt != nil && !grant.ExpiresAt.After(*grant.StartsAt)) {
+				return nil, shared.ErrInvalidParam
+			}
+		}
+		userRoles = append(userRoles, UserRole{
+			UserID:    userId,
+			RoleID:    grant.RoleID,
+			StartsAt:  grant.StartsAt,
+			ExpiresAt: grant.ExpiresAt,
+			Reason:    grant.Reason,
+		})
+	}
+	return userRoles, nil
+}
+
+// ValidateLogin 验证用户登录，连续失败将按用户名和 IP 临时锁定
+func (s *Service) ValidateLogin(ctx context.Context, username, password, ip string) (*User, error) {
+	// 检查是否处于锁定状态
+	if err := s.checkLoginLock(ctx, username, ip); err != nil {
+		return nil, err
+	}
+
 	var user User
 	if err := s.db.WithContext(ctx).Where("username = ?", username).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, shared.ErrLoginFailed
+			return nil, s.loginFailed(ctx, username, ip)
 		}
 		return nil, err
 	}
 
 	// 检查用户状态
-	if user.Status != shared.StatusActive {
+	if user.Status == shared.StatusDisabled {
 		return nil, shared.ErrUserDisabled
 	}
 
 	// 验证密码
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return nil, shared.ErrLoginFailed
+	ok, needsRehash, err := s.hasher.Verify(password, user.Password)
+	if err != nil {
+		logger.Errw(ctx, err, logger.Int("user_id", int(user.ID)))
+	}
+	if !ok {
+		return nil, s.loginFailed(ctx, username, ip)
+	}
+
+	// 哈希算法或参数已过时，使用当前配置重新计算
+	if needsRehash {
+		s.rehashPassword(ctx, &user, password)
 	}
 
+	// 密码正确后再提示未验证邮箱，避免泄露账户状态
+	if user.Status == shared.StatusPending {
+		return nil, shared.ErrEmailNotVerified
+	}
+	if user.Status != shared.StatusActive {
+		return nil, shared.ErrUserDisabled
+	}
+
+	if err := s.clearLoginFailures(ctx, username); err != nil {
+		logger.Errw(ctx, err)
+	}
 	return &user, nil
 }
+
+// loginFailed 记录登录失败并返回登录失败错误
+func (s *Service) loginFailed(ctx context.Context, username, ip string) error {
+	if err := s.recordLoginFailure(ctx, username, ip); err != nil {
+		logger.Errw(ctx, err)
+	}
+	return shared.ErrLoginFailed
+}
@@ -0,0 +1,237 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"go-tpl/logic/shared"
+	"go-tpl/web/types"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildUserRoles(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		v := now.Add(d)
+		return &v
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		userRoles, err := buildUserRoles(7, types.AssignUserRolesReq{
+			RoleIds: []uint{1, 2},
+			Grants: []types.RoleGrantReq{
+				{RoleID: 3, ExpiresAt: at(time.Hour), Reason: "值班"},
+				{RoleID: 4, StartsAt: at(time.Hour), ExpiresAt: at(2 * time.Hour)},
+				{RoleID: 5, StartsAt: at(-time.Hour)},
+				// 生效时间已过、尚未过期
+				{RoleID: 6, StartsAt: at(-2 * time.Hour), ExpiresAt: at(time.Nanosecond)},
+			},
+		}, now)
+		require.NoError(t, err)
+		assert.Equal(t, []UserRole{
+			{UserID: 7, RoleID: 1},
+			{UserID: 7, RoleID: 2},
+			{UserID: 7, RoleID: 3, ExpiresAt: at(time.Hour), Reason: "值班"},
+			{UserID: 7, RoleID: 4, StartsAt: at(time.Hour), ExpiresAt: at(2 * time.Hour)},
+			{UserID: 7, RoleID: 5, StartsAt: at(-time.Hour)},
+			{UserID: 7, RoleID: 6, StartsAt: at(-2 * time.Hour), ExpiresAt: at(time.Nanosecond)},
+		}, userRoles)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		userRoles, err := buildUserRoles(7, types.AssignUserRolesReq{}, now)
+		require.NoError(t, err)
+		assert.Empty(t, userRoles)
+	})
+
+	invalid := []struct {
+		name string
+		req  types.AssignUserRolesReq
+	}{
+		{"duplicate role", types.AssignUserRolesReq{RoleIds: []uint{1, 1}}},
+		{"duplicate grant", types.AssignUserRolesReq{Grants: []types.RoleGrantReq{{RoleID: 1}, {RoleID: 1}}}},
+		{"role and grant", types.AssignUserRolesReq{RoleIds: []uint{1}, Grants: []types.RoleGrantReq{{RoleID: 1, ExpiresAt: at(time.Hour)}}}},
+		{"expires now", types.AssignUserRolesReq{Grants: []types.RoleGrantReq{{RoleID: 1, ExpiresAt: at(0)}}}},
+		{"expired", types.AssignUserRolesReq{Grants: []types.RoleGrantReq{{RoleID: 1, ExpiresAt: at(-time.Hour)}}}},
+		{"expires at start", types.AssignUserRolesReq{Grants: []types.RoleGrantReq{{RoleID: 1, StartsAt: at(time.Hour), ExpiresAt: at(time.Hour)}}}},
+		{"expires before start", types.AssignUserRolesReq{Grants: []types.RoleGrantReq{{RoleID: 1, StartsAt: at(2 * time.Hour), ExpiresAt: at(time.Hour)}}}},
+	}
+	for _, c := range invalid {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := buildUserRoles(7, c.req, now)
+			assert.ErrorIs(t, err, shared.ErrInvalidParam)
+		})
+	}
+}
+
+func setupRoleTest(t *testing.T) (*Service, sqlmock.Sqlmock, *miniredis.Miniredis) {
+	gormDB, mock := setupTestDB(t)
+	mr := miniredis.RunT(t)
+	return NewService(gormDB, redis.NewClient(&redis.Options{Addr: mr.Addr()}), nil, nil, nil), mock, mr
+}
+
+// expectRoleUser 期望读取用户
+func expectRoleUser(mock sqlmock.Sqlmock, userId uint) {
+	mock.ExpectQuery("SELECT \\* FROM `users`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "status"}).AddRow(userId, "alice", shared.StatusActive))
+}
+
+// expectValidRoles 期望校验角色，返回存在且启用的角色
+func expectValidRoles(mock sqlmock.Sqlmock, found ...uint) {
+	rows := sqlmock.NewRows([]string{"id"})
+	for _, id := range found {
+		rows.AddRow(id)
+	}
+	mock.ExpectQuery("SELECT `id` FROM `roles`").WillReturnRows(rows)
+}
+
+// expectLockedRoles 期望在事务中先锁定用户，再读取其现有角色
+func expectLockedRoles(mock sqlmock.Sqlmock, userId uint, existing ...uint) {
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT `id` FROM `users` .* FOR UPDATE").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(userId))
+	rows := sqlmock.NewRows([]string{"user_id", "role_id"})
+	for _, id := range existing {
+		rows.AddRow(userId, id)
+	}
+	mock.ExpectQuery("SELECT \\* FROM `user_roles`").WillReturnRows(rows)
+}
+
+func TestAssignRoles(t *testing.T) {
+	ctx := context.Background()
+	userVersion := fmt.Sprintf(shared.CachePermissionUserVersion, 7)
+
+	t.Run("Diff", func(t *testing.T) {
+		s, mock, mr := setupRoleTest(t)
+		expiresAt := time.Now().Add(time.Hour)
+		expectRoleUser(mock, 7)
+		expectValidRoles(mock, 2, 3, 4)
+		expectLockedRoles(mock, 7, 1, 2)
+		mock.ExpectExec("DELETE FROM `user_roles` WHERE user_id = \\? AND role_id IN \\(\\?\\)").
+			WithArgs(7, 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		// 已有的角色更新有效期和授予原因
+		mock.ExpectExec("UPDATE `user_roles` SET").
+			WithArgs(nil, "", nil, 7, 2).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO `user_roles`").
+			WithArgs(7, 4, nil, nil, "", 7, 3, nil, expiresAt, "值班").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		result, err := s.AssignRoles(ctx, 7, types.AssignUserRolesReq{
+			RoleIds: []uint{2, 4},
+			Grants:  []types.RoleGrantReq{{RoleID: 3, ExpiresAt: &expiresAt, Reason: "值班"}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []uint{4, 3}, result.Added)
+		assert.Equal(t, []uint{1}, result.Removed)
+		require.NoError(t, mock.ExpectationsWereMet())
+		assert.True(t, mr.Exists(userVersion))
+	})
+
+	t.Run("InvalidRoles", func(t *testing.T) {
+		// 不存在或禁用的角色在开启事务前被拒绝
+		s, mock, mr := setupRoleTest(t)
+		expectRoleUser(mock, 7)
+		expectValidRoles(mock, 2)
+
+		_, err := s.AssignRoles(ctx, 7, types.AssignUserRolesReq{RoleIds: []uint{5, 2, 3}})
+		var e shared.Error
+		require.ErrorAs(t, err, &e)
+		assert.Equal(t, shared.ErrRoleInvalid.WithDetail("3, 5"), e)
+		require.NoError(t, mock.ExpectationsWereMet())
+		assert.False(t, mr.Exists(userVersion))
+	})
+
+	t.Run("MissingRoles", func(t *testing.T) {
+		s, _, _ := setupRoleTest(t)
+		_, err := s.AssignRoles(ctx, 7, types.AssignUserRolesReq{})
+		assert.ErrorIs(t, err, shared.ErrInvalidParam)
+	})
+}
+
+func TestAddRole(t *testing.T) {
+	ctx := context.Background()
+	userVersion := fmt.Sprintf(shared.CachePermissionUserVersion, 7)
+
+	t.Run("Added", func(t *testing.T) {
+		s, mock, mr := setupRoleTest(t)
+		expectRoleUser(mock, 7)
+		expectValidRoles(mock, 3)
+		expectLockedRoles(mock, 7, 1)
+		mock.ExpectExec("INSERT INTO `user_roles`").
+			WithArgs(7, 3, nil, nil, "").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		result, err := s.AddRole(ctx, 7, 3, types.AddUserRoleReq{})
+		require.NoError(t, err)
+		assert.Equal(t, []uint{3}, result.Added)
+		require.NoError(t, mock.ExpectationsWereMet())
+		assert.True(t, mr.Exists(userVersion))
+	})
+
+	t.Run("AlreadyAssigned", func(t *testing.T) {
+		s, mock, mr := setupRoleTest(t)
+		expectRoleUser(mock, 7)
+		expectValidRoles(mock, 1)
+		expectLockedRoles(mock, 7, 1)
+		mock.ExpectCommit()
+
+		result, err := s.AddRole(ctx, 7, 1, types.AddUserRoleReq{})
+		require.NoError(t, err)
+		assert.False(t, result.Changed())
+		require.NoError(t, mock.ExpectationsWereMet())
+		assert.False(t, mr.Exists(userVersion))
+	})
+
+	t.Run("DisabledRole", func(t *testing.T) {
+		s, mock, _ := setupRoleTest(t)
+		expectRoleUser(mock, 7)
+		expectValidRoles(mock)
+
+		_, err := s.AddRole(ctx, 7, 1, types.AddUserRoleReq{})
+		var e shared.Error
+		require.ErrorAs(t, err, &e)
+		assert.Equal(t, shared.ErrRoleInvalid.Code, e.Code)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRemoveRole(t *testing.T) {
+	ctx := context.Background()
+	userVersion := fmt.Sprintf(shared.CachePermissionUserVersion, 7)
+
+	cases := []struct {
+		name     string
+		affected int64
+		want     []uint
+	}{
+		{"removed", 1, []uint{1}},
+		{"not assigned", 0, []uint{}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s, mock, mr := setupRoleTest(t)
+			expectRoleUser(mock, 7)
+			mock.ExpectBegin()
+			mock.ExpectExec("DELETE FROM `user_roles` WHERE user_id = \\? AND role_id = \\?").
+				WithArgs(7, 1).
+				WillReturnResult(sqlmock.NewResult(0, c.affected))
+			mock.ExpectCommit()
+
+			result, err := s.RemoveRole(ctx, 7, 1)
+			require.NoError(t, err)
+			assert.Equal(t, c.want, result.Removed)
+			require.NoError(t, mock.ExpectationsWereMet())
+			assert.Equal(t, c.affected > 0, mr.Exists(userVersion))
+		})
+	}
+}
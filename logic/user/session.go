@@ -0,0 +1,116 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"go-tpl/infra/jwt"
+	"go-tpl/logic/shared"
+	"time"
+)
+
+const sessionTouchInterval = time.Minute
+
+// CreateSession 记录一次登录产生的会话
+func (s *Service) CreateSession(ctx context.Context, userID uint, familyID, userAgent, ip string) error {
+	session := Session{
+		UserID:     userID,
+		FamilyID:   familyID,
+		UserAgent:  shared.Truncate(userAgent, shared.MaxUserAgentLength),
+		IP:         ip,
+		LastSeenAt: time.Now(),
+	}
+	return s.db.WithContext(ctx).Create(&session).Error
+}
+
+// TouchSession 更新会话最近活跃时间和 IP，同一会话每分钟最多写一次数据库
+func (s *Service) TouchSession(ctx context.Context, familyID, ip string) error {
+	ok, err := s.redis.SetNX(ctx, fmt.Sprintf(shared.CacheSessionTouch, familyID), 1, sessionTouchInterval).Result()
+	if err != nil || !ok {
+		return err
+	}
+
+	return s.db.WithContext(ctx).Model(&Session{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Updates(map[string]interface{}{
+			"last_seen_at": time.Now(),
+			"ip":           ip,
+		}).Error
+}
+
+// ListSessions 获取用户当前有效的会话，currentFamilyID 对应的会话标记为当前会话
+func (s *Service) ListSessions(ctx context.Context, userID uint, currentFamilyID string) ([]Session, error) {
+	var sessions []Session
+	if err := s.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	familyIDs := make([]string, len(sessions))
+	for i, session := range sessions {
+		familyIDs[i] = session.FamilyID
+	}
+	active, err := jwt.ActiveFamilies(ctx, familyIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// 过滤已过期或因 refresh token 重放被吊销的会话，并顺带标记为已吊销
+	list := make([]Session, 0, len(sessions))
+	var staleIDs []uint
+	for _, session := range sessions {
+		if !active[session.FamilyID] {
+			staleIDs = append(staleIDs, session.ID)
+			continue
+		}
+		session.Current = currentFamilyID != "" && session.FamilyID == currentFamilyID
+		list = append(list, session)
+	}
+	if len(staleIDs) > 0 {
+		if err = s.db.WithContext(ctx).Model(&Session{}).
+			Where("id IN ?", staleIDs).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// RevokeSession 吊销用户的指定会话
+func (s *Service) RevokeSession(ctx context.Context, userID, sessionID uint) error {
+	var session Session
+	result := s.db.WithContext(ctx).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		Limit(1).Find(&session)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return shared.ErrRecordNotFound
+	}
+
+	return s.RevokeSessionByFamily(ctx, session.FamilyID)
+}
+
+// RevokeSessionByFamily 吊销 refresh token 家族对应的会话
+func (s *Service) RevokeSessionByFamily(ctx context.Context, familyID string) error {
+	if err := jwt.RevokeFamily(ctx, familyID); err != nil {
+		return err
+	}
+
+	return s.db.WithContext(ctx).Model(&Session{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeAllSessions 吊销用户的全部会话及已签发的 token
+func (s *Service) RevokeAllSessions(ctx context.Context, userID uint) error {
+	if err := jwt.RevokeUserTokens(ctx, userID); err != nil {
+		return err
+	}
+
+	return s.db.WithContext(ctx).Model(&Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
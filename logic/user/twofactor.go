@@ -0,0 +1,284 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-tpl/infra"
+	"go-tpl/infra/totp"
+	"go-tpl/logic/shared"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	recoveryCodeCount   = 10
+	twoFactorPendingTTL = 10 * time.Minute
+)
+
+// TwoFactorEnrollment 两步验证绑定信息
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// EnrollTwoFactor 生成待确认的 TOTP 密钥，需调用 ConfirmTwoFactor 完成绑定
+func (s *Service) EnrollTwoFactor(ctx context.Context, id uint) (*TwoFactorEnrollment, error) {
+	user, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled {
+		return nil, shared.ErrTwoFactorEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err = s.redis.Set(ctx, fmt.Sprintf(shared.CacheTwoFactorPending, id), secret, twoFactorPendingTTL).Err(); err != nil {
+		return nil, err
+	}
+
+	issuer := infra.Cfg.TwoFactor.Issuer
+	if issuer == "" {
+		issuer = "go-tpl"
+	}
+	return &TwoFactorEnrollment{
+		Secret: secret,
+		URI:    totp.URI(issuer, user.Username, secret),
+	}, nil
+}
+
+// ConfirmTwoFactor 校验验证码并开启两步验证，返回一次性恢复码
+func (s *Service) ConfirmTwoFactor(ctx context.Context, id uint, code string) ([]string, error) {
+	user, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled {
+		return nil, shared.ErrTwoFactorEnabled
+	}
+
+	key := fmt.Sprintf(shared.CacheTwoFactorPending, id)
+	secret, err := s.redis.Get(ctx, key).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, shared.ErrTwoFactorExpired
+		}
+		return nil, err
+	}
+
+	if err = s.verifyTOTP(ctx, id, secret, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&User{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"two_factor_enabled": true,
+			"two_factor_secret":  secret,
+		}).Error; err != nil {
+			return err
+		}
+		return replaceRecoveryCodes(tx, id, hashes)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.redis.Del(ctx, key).Err(); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTwoFactor 校验验证码或恢复码后关闭两步验证
+func (s *Service) DisableTwoFactor(ctx context.Context, id uint, code string) error {
+	user, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !user.TwoFactorEnabled {
+		return shared.ErrTwoFactorNotEnabled
+	}
+
+	if err = s.verifyTwoFactorAttempt(ctx, user, func() error {
+		return s.verifyTwoFactor(ctx, user, code)
+	}); err != nil {
+		return err
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&User{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"two_factor_enabled": false,
+			"two_factor_secret":  "",
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", id).Delete(&RecoveryCode{}).Error
+	})
+}
+
+// RegenerateRecoveryCodes 校验验证码后重新生成恢复码，旧恢复码全部失效
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, id uint, code string) ([]string, error) {
+	user, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TwoFactorEnabled {
+		return nil, shared.ErrTwoFactorNotEnabled
+	}
+
+	if err = s.verifyTwoFactorAttempt(ctx, user, func() error {
+		return s.verifyTOTP(ctx, id, user.TwoFactorSecret, code)
+	}); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, id, hashes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// ValidateTwoFactorLogin 登录第二步：校验 TOTP 或恢复码，失败计入登录失败次数
+func (s *Service) ValidateTwoFactorLogin(ctx context.Context, id uint, code, ip string) (*User, error) {
+	user, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.checkLoginLock(ctx, user.Username, ip); err != nil {
+		return nil, err
+	}
+	if user.Status != shared.StatusActive {
+		return nil, shared.ErrUserDisabled
+	}
+	if !user.TwoFactorEnabled {
+		return nil, shared.ErrTwoFactorNotEnabled
+	}
+
+	if err = s.verifyTwoFactor(ctx, user, code); err != nil {
+		if errors.Is(err, shared.ErrInvalidOTP) {
+			if lockErr := s.recordLoginFailure(ctx, user.Username, ip); lockErr != nil {
+				return nil, lockErr
+			}
+		}
+		return nil, err
+	}
+
+	if err = s.clearLoginFailures(ctx, user.Username); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// verifyTwoFactorAttempt 已登录用户管理两步验证时校验验证码，与登录第二步共用失败计数和锁定，
+// 防止持有会话者暴力破解验证码或恢复码
+func (s *Service) verifyTwoFactorAttempt(ctx context.Context, user *User, verify func() error) error {
+	if err := s.checkLoginLock(ctx, user.Username, ""); err != nil {
+		return err
+	}
+
+	if err := verify(); err != nil {
+		if errors.Is(err, shared.ErrInvalidOTP) {
+			if lockErr := s.recordLoginFailure(ctx, user.Username, ""); lockErr != nil {
+				return lockErr
+			}
+		}
+		return err
+	}
+	return s.clearLoginFailures(ctx, user.Username)
+}
+
+// verifyTwoFactor 校验 TOTP 验证码，非 6 位数字时按恢复码处理
+func (s *Service) verifyTwoFactor(ctx context.Context, user *User, code string) error {
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		return s.verifyTOTP(ctx, user.ID, user.TwoFactorSecret, code)
+	}
+	return s.useRecoveryCode(ctx, user.ID, code)
+}
+
+// verifyTOTP 校验 TOTP 验证码，同一时间步的验证码只能使用一次
+func (s *Service) verifyTOTP(ctx context.Context, id uint, secret, code string) error {
+	step, ok := totp.Validate(secret, code, time.Now(), 1)
+	if !ok {
+		return shared.ErrInvalidOTP
+	}
+
+	// 记录已使用的时间步，覆盖容错窗口即可
+	used, err := s.redis.SetNX(ctx, fmt.Sprintf(shared.CacheTwoFactorUsed, id, step), 1, 3*totp.Period*time.Second).Result()
+	if err != nil {
+		return err
+	}
+	if !used {
+		return shared.ErrInvalidOTP
+	}
+	return nil
+}
+
+// useRecoveryCode 核销一个未使用的恢复码
+func (s *Service) useRecoveryCode(ctx context.Context, id uint, code string) error {
+	result := s.db.WithContext(ctx).Model(&RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", id, hashRecoveryCode(code)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return shared.ErrInvalidOTP
+	}
+	return nil
+}
+
+// replaceRecoveryCodes 替换用户的全部恢复码
+func replaceRecoveryCodes(tx *gorm.DB, id uint, hashes []string) error {
+	if err := tx.Where("user_id = ?", id).Delete(&RecoveryCode{}).Error; err != nil {
+		return err
+	}
+
+	recoveryCodes := make([]RecoveryCode, len(hashes))
+	for i, hash := range hashes {
+		recoveryCodes[i] = RecoveryCode{UserID: id, CodeHash: hash}
+	}
+	return tx.Create(&recoveryCodes).Error
+}
+
+// generateRecoveryCodes 生成恢复码及其哈希，格式 xxxxx-xxxxx
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := hex.EncodeToString(b)
+		codes[i] = raw[:5] + "-" + raw[5:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode 恢复码为高熵随机值，使用 SHA-256 哈希即可
+func hashRecoveryCode(code string) string {
+	return shared.HashToken(strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", "")))
+}
@@ -7,7 +7,10 @@ package logic
 
 import (
 	"go-tpl/infra"
+	"go-tpl/logic/apikey"
+	"go-tpl/logic/audit"
 	"go-tpl/logic/auth"
+	"go-tpl/logic/dept"
 	"go-tpl/logic/permission"
 	"go-tpl/logic/role"
 	"go-tpl/logic/user"
@@ -20,11 +23,17 @@ type Service struct {
 	User       *user.Service
 	Role       *role.Service
 	Permission *permission.Service
+	APIKey     *apikey.Service
+	Dept       *dept.Service
 }
 
 var providerSet = wire.NewSet(
-	infra.ProvideDB,  // 提供DB
-	infra.ProvideRDB, // 提供Redis
+	infra.ProvideDB,             // 提供DB
+	infra.ProvideRDB,            // 提供Redis
+	infra.ProvideMailer,         // 提供邮件发送
+	infra.ProvideOIDC,           // 提供 OIDC 提供方
+	infra.ProvidePasswordPolicy, // 提供密码策略
+	infra.ProvidePasswordHasher, // 提供密码哈希
 )
 
 var svcSet = wire.NewSet(
@@ -32,6 +41,9 @@ var svcSet = wire.NewSet(
 	role.NewService,
 	permission.NewService,
 	auth.NewService,
+	apikey.NewService,
+	audit.NewService,
+	dept.NewService,
 )
 
 func initialize() *Service {
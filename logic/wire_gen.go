@@ -9,7 +9,10 @@ package logic
 import (
 	"github.com/google/wire"
 	"go-tpl/infra"
+	"go-tpl/logic/apikey"
+	"go-tpl/logic/audit"
 	"go-tpl/logic/auth"
+	"go-tpl/logic/dept"
 	"go-tpl/logic/permission"
 	"go-tpl/logic/role"
 	"go-tpl/logic/user"
@@ -20,15 +23,24 @@ import (
 func initialize() *Service {
 	db := infra.ProvideDB()
 	client := infra.ProvideRDB()
-	service := user.NewService(db, client)
-	authService := auth.NewService(service)
-	roleService := role.NewService(db)
-	permissionService := permission.NewService(db)
+	policy := infra.ProvidePasswordPolicy()
+	hasher := infra.ProvidePasswordHasher()
+	deptService := dept.NewService(db)
+	service := user.NewService(db, client, policy, hasher, deptService)
+	roleService := role.NewService(db, client)
+	permissionService := permission.NewService(db, client, roleService)
+	auditService := audit.NewService(db)
+	mailer := infra.ProvideMailer()
+	providers := infra.ProvideOIDC()
+	authService := auth.NewService(service, permissionService, auditService, client, mailer, providers)
+	apikeyService := apikey.NewService(db, client, service, permissionService)
 	logicService := &Service{
 		Auth:       authService,
 		User:       service,
 		Role:       roleService,
 		Permission: permissionService,
+		APIKey:     apikeyService,
+		Dept:       deptService,
 	}
 	return logicService
 }
@@ -40,8 +52,10 @@ type Service struct {
 	User       *user.Service
 	Role       *role.Service
 	Permission *permission.Service
+	APIKey     *apikey.Service
+	Dept       *dept.Service
 }
 
-var providerSet = wire.NewSet(infra.ProvideDB, infra.ProvideRDB)
+var providerSet = wire.NewSet(infra.ProvideDB, infra.ProvideRDB, infra.ProvideMailer, infra.ProvideOIDC, infra.ProvidePasswordPolicy, infra.ProvidePasswordHasher)
 
-var svcSet = wire.NewSet(user.NewService, role.NewService, permission.NewService, auth.NewService)
+var svcSet = wire.NewSet(user.NewService, role.NewService, permission.NewService, auth.NewService, apikey.NewService, audit.NewService, dept.NewService)
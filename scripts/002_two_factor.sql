@@ -0,0 +1,18 @@
+-- 两步验证
+-- 创建日期: 2026-10-18
+
+USE app_db;
+
+ALTER TABLE users
+    ADD COLUMN two_factor_enabled TINYINT(1) NOT NULL DEFAULT 0 COMMENT '是否开启两步验证',
+    ADD COLUMN two_factor_secret VARCHAR(64) NOT NULL DEFAULT '' COMMENT 'TOTP 密钥';
+
+-- 两步验证恢复码表
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL COMMENT '用户ID',
+    code_hash VARCHAR(64) NOT NULL COMMENT '恢复码哈希',
+    used_at TIMESTAMP NULL DEFAULT NULL COMMENT '使用时间',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    INDEX idx_user_id (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='两步验证恢复码表';
@@ -0,0 +1,18 @@
+-- 登录会话
+-- 创建日期: 2026-10-18
+
+USE app_db;
+
+-- 登录会话表，每条记录对应一个 refresh token 家族
+CREATE TABLE IF NOT EXISTS user_sessions (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL COMMENT '用户ID',
+    family_id VARCHAR(32) NOT NULL COMMENT 'refresh token 家族ID',
+    user_agent VARCHAR(255) NOT NULL DEFAULT '' COMMENT '客户端 User-Agent',
+    ip VARCHAR(45) NOT NULL DEFAULT '' COMMENT '最近访问 IP',
+    last_seen_at TIMESTAMP NULL DEFAULT NULL COMMENT '最近活跃时间',
+    revoked_at TIMESTAMP NULL DEFAULT NULL COMMENT '吊销时间',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    UNIQUE KEY uk_family_id (family_id),
+    INDEX idx_user_id (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='登录会话表';
@@ -0,0 +1,22 @@
+-- 个人访问令牌 (API Key)
+-- 创建日期: 2026-10-18
+
+USE app_db;
+
+-- API Key 表，仅保存哈希
+CREATE TABLE IF NOT EXISTS api_keys (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL COMMENT '所属用户ID',
+    name VARCHAR(100) NOT NULL COMMENT '名称',
+    prefix VARCHAR(16) NOT NULL COMMENT '明文前缀',
+    key_hash VARCHAR(64) NOT NULL COMMENT 'Key 哈希',
+    permissions TEXT COMMENT '权限代码列表 (JSON)',
+    expires_at TIMESTAMP NULL DEFAULT NULL COMMENT '过期时间',
+    last_used_at TIMESTAMP NULL DEFAULT NULL COMMENT '最近使用时间',
+    last_used_ip VARCHAR(45) NOT NULL DEFAULT '' COMMENT '最近使用 IP',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    deleted_at TIMESTAMP NULL DEFAULT NULL COMMENT '删除时间',
+    UNIQUE KEY uk_key_hash (key_hash),
+    INDEX idx_user_id (user_id),
+    INDEX idx_deleted_at (deleted_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='API Key 表';
@@ -0,0 +1,16 @@
+-- 第三方登录 (OIDC) 身份绑定
+-- 创建日期: 2026-10-18
+
+USE app_db;
+
+-- 外部身份绑定表
+CREATE TABLE IF NOT EXISTS user_identities (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL COMMENT '用户ID',
+    provider VARCHAR(50) NOT NULL COMMENT '身份提供方名称',
+    subject VARCHAR(255) NOT NULL COMMENT '提供方用户标识 (sub)',
+    email VARCHAR(100) NOT NULL DEFAULT '' COMMENT '绑定时的邮箱',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    UNIQUE KEY uk_provider_subject (provider, subject),
+    INDEX idx_user_id (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='外部身份绑定表';
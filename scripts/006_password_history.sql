@@ -0,0 +1,13 @@
+-- 密码历史
+-- 创建日期: 2026-10-18
+
+USE app_db;
+
+-- 历史密码哈希表，用于禁止重复使用最近的密码
+CREATE TABLE IF NOT EXISTS user_password_histories (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL COMMENT '用户ID',
+    password_hash VARCHAR(255) NOT NULL COMMENT '密码哈希',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    INDEX idx_user_id (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='密码历史表';
@@ -0,0 +1,29 @@
+-- 审计记录
+-- 创建日期: 2026-10-18
+
+USE app_db;
+
+-- 审计记录表，记录模拟登录等敏感操作
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    actor_id BIGINT NOT NULL COMMENT '实际操作人ID',
+    action VARCHAR(64) NOT NULL COMMENT '操作',
+    target_id BIGINT NOT NULL DEFAULT 0 COMMENT '被操作用户ID',
+    reason VARCHAR(255) NOT NULL DEFAULT '' COMMENT '操作原因',
+    ip VARCHAR(45) NOT NULL DEFAULT '' COMMENT 'IP',
+    user_agent VARCHAR(255) NOT NULL DEFAULT '' COMMENT 'User-Agent',
+    detail TEXT COMMENT '详细信息 (JSON)',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    INDEX idx_actor_id (actor_id),
+    INDEX idx_target_id (target_id),
+    INDEX idx_action_created_at (action, created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='审计记录表';
+
+-- 模拟登录权限，默认仅授予管理员
+INSERT IGNORE INTO permissions (code, name, description, module) VALUES
+('user:impersonate', '模拟登录', '允许以其他用户身份访问系统，用于问题排查', 'user');
+
+INSERT IGNORE INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r, permissions p
+WHERE r.name = 'admin' AND p.code = 'user:impersonate';
@@ -0,0 +1,13 @@
+-- 角色继承
+-- 创建日期: 2026-10-18
+
+USE app_db;
+
+-- 角色继承关系表，角色继承上级角色及其祖先的全部权限
+CREATE TABLE IF NOT EXISTS role_parents (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    role_id BIGINT NOT NULL COMMENT '角色ID',
+    parent_id BIGINT NOT NULL COMMENT '上级角色ID',
+    UNIQUE KEY uk_role_parent (role_id, parent_id),
+    INDEX idx_parent_id (parent_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='角色继承关系表';
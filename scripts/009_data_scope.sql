@@ -0,0 +1,47 @@
+-- 部门与数据范围
+-- 创建日期: 2026-10-18
+
+USE app_db;
+
+-- 部门表
+CREATE TABLE IF NOT EXISTS departments (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    parent_id BIGINT NOT NULL DEFAULT 0 COMMENT '上级部门ID，0 表示顶级部门',
+    name VARCHAR(50) NOT NULL COMMENT '部门名称',
+    sort INT NOT NULL DEFAULT 0 COMMENT '排序',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    deleted_at TIMESTAMP NULL DEFAULT NULL COMMENT '删除时间',
+    INDEX idx_parent_id (parent_id),
+    INDEX idx_deleted_at (deleted_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='部门表';
+
+-- 用户所属部门
+ALTER TABLE users
+    ADD COLUMN dept_id BIGINT NOT NULL DEFAULT 0 COMMENT '所属部门ID，0 表示未分配' AFTER status,
+    ADD INDEX idx_dept_id (dept_id);
+
+-- 角色数据范围，默认全部数据以保持原有行为
+ALTER TABLE roles
+    ADD COLUMN data_scope TINYINT NOT NULL DEFAULT 1 COMMENT '数据范围: 1-全部, 2-自定义部门, 3-本部门, 4-本部门及下级, 5-仅本人' AFTER status;
+
+-- 角色自定义数据范围的部门
+CREATE TABLE IF NOT EXISTS role_depts (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    role_id BIGINT NOT NULL COMMENT '角色ID',
+    dept_id BIGINT NOT NULL COMMENT '部门ID',
+    UNIQUE KEY uk_role_dept (role_id, dept_id),
+    INDEX idx_dept_id (dept_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='角色数据范围部门表';
+
+-- 部门管理权限
+INSERT IGNORE INTO permissions (code, name, description, module) VALUES
+('dept:create', '创建部门', '允许创建新部门', 'dept'),
+('dept:read', '查看部门', '允许查看部门信息', 'dept'),
+('dept:update', '更新部门', '允许更新部门信息', 'dept'),
+('dept:delete', '删除部门', '允许删除部门', 'dept');
+
+INSERT IGNORE INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r, permissions p
+WHERE r.name = 'admin' AND p.module = 'dept';
@@ -0,0 +1,13 @@
+-- 通配符权限
+-- 创建日期: 2026-10-18
+
+USE app_db;
+
+-- 超级权限，匹配全部权限代码；授予管理员后新增的权限无需再逐一分配
+INSERT IGNORE INTO permissions (code, name, description, module) VALUES
+('*', '超级权限', '匹配全部权限', 'system');
+
+INSERT IGNORE INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r, permissions p
+WHERE r.name = 'admin' AND p.code = '*';
@@ -0,0 +1,13 @@
+-- 权限树（菜单、按钮、接口）
+-- 创建日期: 2026-10-18
+
+USE app_db;
+
+-- 已有权限均为接口权限，作为顶级节点保留
+ALTER TABLE permissions
+    ADD COLUMN parent_id BIGINT NOT NULL DEFAULT 0 COMMENT '上级权限ID，0 表示顶级权限' AFTER id,
+    ADD COLUMN type VARCHAR(10) NOT NULL DEFAULT 'api' COMMENT '类型: menu-菜单, button-按钮, api-接口' AFTER module,
+    ADD COLUMN path VARCHAR(255) NOT NULL DEFAULT '' COMMENT '前端路由路径' AFTER type,
+    ADD COLUMN icon VARCHAR(100) NOT NULL DEFAULT '' COMMENT '图标' AFTER path,
+    ADD COLUMN sort INT NOT NULL DEFAULT 0 COMMENT '排序' AFTER icon,
+    ADD INDEX idx_parent_id (parent_id);
@@ -0,0 +1,11 @@
+-- 限时角色授予
+-- 创建日期: 2026-10-18
+
+USE app_db;
+
+-- 已有的用户角色均为长期有效
+ALTER TABLE user_roles
+    ADD COLUMN starts_at TIMESTAMP NULL DEFAULT NULL COMMENT '生效时间，为空表示立即生效' AFTER role_id,
+    ADD COLUMN expires_at TIMESTAMP NULL DEFAULT NULL COMMENT '过期时间，为空表示永不过期' AFTER starts_at,
+    ADD COLUMN reason VARCHAR(255) NOT NULL DEFAULT '' COMMENT '授予原因' AFTER expires_at,
+    ADD INDEX idx_expires_at (expires_at);
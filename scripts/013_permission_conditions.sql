@@ -0,0 +1,11 @@
+-- 基于属性的权限访问条件
+-- 创建日期: 2026-10-18
+
+USE app_db;
+
+-- 已有的权限和角色权限关联均为无条件授予
+ALTER TABLE permissions
+    ADD COLUMN condition_expr VARCHAR(1024) NOT NULL DEFAULT '' COMMENT '访问条件表达式，为空表示无条件' AFTER sort;
+
+ALTER TABLE role_permissions
+    ADD COLUMN condition_expr VARCHAR(1024) NOT NULL DEFAULT '' COMMENT '仅对该角色生效的附加访问条件，为空表示无条件' AFTER permission_id;
@@ -0,0 +1,7 @@
+-- 修改邮箱需验证新邮箱
+-- 创建日期: 2026-10-18
+
+USE app_db;
+
+ALTER TABLE users
+    ADD COLUMN pending_email VARCHAR(100) NOT NULL DEFAULT '' COMMENT '自行修改后尚未验证的新邮箱，验证通过后替换 email' AFTER email;
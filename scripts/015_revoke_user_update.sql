@@ -0,0 +1,11 @@
+-- 收回普通用户角色的 user:update 权限
+-- 创建日期: 2026-10-18
+-- 普通用户通过 /api/me 维护自己的资料和密码；user:update 可修改任意用户的密码、邮箱和状态，仅应授予管理员
+-- 执行后用户权限缓存最长 10 分钟后刷新，需立即生效时在 Redis 中执行 INCR perm:version
+
+USE app_db;
+
+DELETE rp FROM role_permissions rp
+JOIN roles r ON r.id = rp.role_id
+JOIN permissions p ON p.id = rp.permission_id
+WHERE r.name = 'user' AND p.code = 'user:update';
@@ -8,9 +8,15 @@ import (
 	"go-tpl/infra/logger/logx"
 	"go-tpl/infra/monitor"
 	"go-tpl/logic"
+	"go-tpl/web/middleware"
 	"go-tpl/web/rest"
+	"go-tpl/web/rest/apikey"
+	"go-tpl/web/rest/dept"
+	"go-tpl/web/rest/me"
 	"go-tpl/web/rest/permission"
 	"go-tpl/web/rest/role"
+	"go-tpl/web/rest/session"
+	"go-tpl/web/rest/twofactor"
 	"go-tpl/web/rest/user"
 	"log"
 	"net/http"
@@ -23,12 +29,17 @@ import (
 )
 
 type App struct {
-	engine *gin.Engine
+	engine   *gin.Engine
+	stopJobs context.CancelFunc // 停止后台任务
 }
 
 func New() *App {
+	engine := gin.Default()
+	// gin.Context 作为 context.Context 传入业务层时，回退到 Request.Context() 取值（调用者、日志字段等）
+	engine.ContextWithFallback = true
+
 	app := &App{
-		engine: gin.Default(),
+		engine: engine,
 	}
 
 	app.init()
@@ -44,6 +55,15 @@ func (a *App) init() {
 
 	// 3.配置web路由
 	a.setupRouter()
+	// 4.同步路由声明的权限代码
+	if err := logic.Svc.Permission.SyncRoutes(context.Background(), middleware.PermissionRoutes()); err != nil {
+		panic(fmt.Sprintf("Failed to sync route permissions: %v", err))
+	}
+
+	// 5.启动后台任务
+	var ctx context.Context
+	ctx, a.stopJobs = context.WithCancel(context.Background())
+	go logic.Svc.User.RunRoleSweeper(ctx)
 }
 
 func (a *App) Run() {
@@ -62,6 +82,7 @@ func (a *App) Run() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("Shutting down server...")
+	a.stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -80,6 +101,9 @@ func (a *App) setupRouter() {
 	//monitor.StartPprof()
 	monitor.RegisterMetrics(r)
 
+	// JWT 公钥，供下游服务验证 token
+	r.GET("/.well-known/jwks.json", rest.JWKS)
+
 	api := r.Group("/api")
 	// 公共中间件
 	api.Use(logx.GinLogMiddleware())
@@ -87,11 +111,25 @@ func (a *App) setupRouter() {
 	// 公共路由
 	api.GET("/test", rest.Test)
 	api.POST("/register", rest.Register)
+	api.POST("/register/verify", rest.VerifyEmail)
+	api.POST("/register/resend", rest.ResendVerification)
 	api.POST("/login", rest.Login)
+	api.POST("/login/2fa", rest.LoginTwoFactor)
+	api.GET("/oidc/providers", rest.OIDCProviders)
+	api.GET("/oidc/:provider/authorize", rest.OIDCAuthorize)
+	api.POST("/oidc/:provider/callback", rest.OIDCCallback)
 	api.POST("/refresh", rest.RefreshToken)
+	api.POST("/password/forgot", rest.ForgotPassword)
+	api.POST("/password/reset", rest.ResetPassword)
+	api.POST("/logout", middleware.TokenAuth(), middleware.RequireSession(), rest.Logout)
 
 	// 注册接口处理
+	me.Register(api)
 	user.Register(api)
 	role.Register(api)
 	permission.Register(api)
+	dept.Register(api)
+	twofactor.Register(api)
+	session.Register(api)
+	apikey.Register(api)
 }
@@ -2,6 +2,9 @@ package middleware
 
 import (
 	"go-tpl/infra/jwt"
+	"go-tpl/infra/logger"
+	"go-tpl/logic"
+	"go-tpl/logic/apikey"
 	"go-tpl/logic/shared"
 	"go-tpl/web/base"
 	"strings"
@@ -13,9 +16,11 @@ const (
 	AuthorizationHeader = "Authorization"
 	BearerPrefix        = "Bearer "
 	UserIDKey           = "user_id"
+	ClaimsKey           = "claims"
+	APIKeyKey           = "api_key"
 )
 
-// TokenAuth JWT-Token 认证中间件
+// TokenAuth JWT-Token 认证中间件，同时接受以 pat_ 开头的 API Key
 func TokenAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 从 Header 中获取 token
@@ -38,10 +43,25 @@ func TokenAuth() gin.HandlerFunc {
 			return
 		}
 
+		// API Key 认证
+		if apikey.IsAPIKey(tokenString) {
+			key, err := logic.Svc.APIKey.Authenticate(c, tokenString, c.ClientIP())
+			if err != nil {
+				base.FailWithError(c, err)
+				return
+			}
+
+			c.Request = c.Request.WithContext(shared.WithCaller(c.Request.Context(), key.UserID))
+			c.Set(UserIDKey, key.UserID)
+			c.Set(APIKeyKey, key)
+			c.Next()
+			return
+		}
+
 		// 解析 token
 		claims, err := jwt.ParseToken(tokenString)
 		if err != nil {
-			base.FailWithError(c, shared.ErrInvalidToken)
+			base.FailWithError(c, shared.TokenError(err))
 			return
 		}
 
@@ -51,9 +71,57 @@ func TokenAuth() gin.HandlerFunc {
 			return
 		}
 
+		// 检查 token 是否已被吊销（登出、禁用、修改密码等）
+		revoked, err := jwt.IsRevoked(c, claims)
+		if err != nil {
+			logger.Errw(c, err)
+			base.FailWithError(c, err)
+			return
+		}
+		if revoked {
+			base.FailWithError(c, shared.ErrInvalidToken)
+			return
+		}
+
+		// 更新会话最近活跃时间，失败不影响请求
+		if claims.FamilyID != "" {
+			if err = logic.Svc.User.TouchSession(c, claims.FamilyID, c.ClientIP()); err != nil {
+				logger.Errw(c, err)
+			}
+		}
+
+		// 请求日志携带用户信息，模拟登录时同时记录实际操作人；业务层按调用者应用数据范围
+		fields := []logger.Field{logger.Int("user_id", int(claims.UserID))}
+		if claims.Impersonated() {
+			fields = append(fields, logger.Int("actor_id", int(claims.Actor.UserID)))
+		}
+		ctx := logger.CtxWithField(c.Request.Context(), fields...)
+		c.Request = c.Request.WithContext(shared.WithCaller(ctx, claims.UserID))
+		if claims.Impersonated() {
+			logger.Info(c, "impersonated request",
+				logger.Str("path", c.Request.Method+" "+c.Request.URL.Path))
+		}
+
 		// 将用户信息存入上下文
 		c.Set(UserIDKey, claims.UserID)
+		c.Set(ClaimsKey, claims)
+
+		c.Next()
+	}
+}
 
+// RequireSession 要求请求使用用户本人的登录会话（JWT）认证，拒绝 API Key 和模拟登录 token，需在 TokenAuth 之后使用
+// 用于管理账户安全设置的接口，避免 API Key 被用于签发新的 Key 或修改两步验证等
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := GetAPIKey(c); ok {
+			base.FailWithError(c, shared.ErrNoPermission)
+			return
+		}
+		if claims, ok := GetClaims(c); ok && claims.Impersonated() {
+			base.FailWithError(c, shared.ErrNoPermission)
+			return
+		}
 		c.Next()
 	}
 }
@@ -68,3 +136,25 @@ func GetUserID(c *gin.Context) (uint, bool) {
 	userID, ok := value.(uint)
 	return userID, ok
 }
+
+// GetClaims 从上下文获取当前 token 的 Claims
+func GetClaims(c *gin.Context) (*jwt.Claims, bool) {
+	value, exists := c.Get(ClaimsKey)
+	if !exists {
+		return nil, false
+	}
+
+	claims, ok := value.(*jwt.Claims)
+	return claims, ok
+}
+
+// GetAPIKey 从上下文获取当前请求使用的 API Key，JWT 认证的请求返回 false
+func GetAPIKey(c *gin.Context) (*apikey.APIKey, bool) {
+	value, exists := c.Get(APIKeyKey)
+	if !exists {
+		return nil, false
+	}
+
+	key, ok := value.(*apikey.APIKey)
+	return key, ok
+}
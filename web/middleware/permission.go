@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"go-tpl/infra/logger"
+	"go-tpl/logic"
+	"go-tpl/logic/permission"
+	"go-tpl/logic/shared"
+	"go-tpl/web/base"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission 权限校验中间件，需在 TokenAuth 之后使用
+func RequirePermission(code string) gin.HandlerFunc {
+	return requirePermission(code, "")
+}
+
+// requirePermission 权限校验，module 为路由所属模块，用于在访问条件引用 resource 时加载目标资源
+func requirePermission(code, module string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := GetUserID(c)
+		if !ok {
+			base.FailWithError(c, shared.ErrNoToken)
+			return
+		}
+
+		// 记录访问条件可引用的请求属性，属性在条件求值时才读取
+		c.Request = c.Request.WithContext(permission.WithAttributes(c.Request.Context(), permission.Attributes{
+			IP:       c.ClientIP(),
+			Time:     time.Now(),
+			Resource: resourceLoader(c, module),
+		}))
+
+		var (
+			allowed bool
+			err     error
+		)
+		if key, isAPIKey := GetAPIKey(c); isAPIKey {
+			// API Key 仅拥有其权限范围与所属用户当前权限的交集
+			allowed, err = logic.Svc.APIKey.HasPermission(c, key, code)
+		} else {
+			// 通过 用户角色 -> 角色权限 -> 权限 解析用户有效权限
+			allowed, err = logic.Svc.Permission.HasPermission(c, userID, code)
+		}
+		if err != nil {
+			logger.Errw(c, err)
+			base.FailWithError(c, err)
+			return
+		}
+		if !allowed {
+			base.FailWithError(c, shared.ErrNoPermission)
+			return
+		}
+
+		c.Next()
+	}
+}
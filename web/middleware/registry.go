@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"go-tpl/logic/permission"
+	"net/http"
+	"path"
+
+	"github.com/gin-gonic/gin"
+)
+
+// permissionRoutes 路由声明的权限代码，在启动注册路由时写入，之后只读
+var permissionRoutes []permission.Route
+
+// PermissionRoutes 获取已注册的路由与权限代码
+func PermissionRoutes() []permission.Route {
+	return permissionRoutes
+}
+
+// Guarded 需要权限的路由组，注册路由时记录其权限代码并挂载 RequirePermission
+type Guarded struct {
+	group  *gin.RouterGroup
+	module string
+}
+
+// Guard 包装路由组，模块名取路由组路径的最后一段（如 /api/user -> user）
+func Guard(group *gin.RouterGroup) *Guarded {
+	return &Guarded{
+		group:  group,
+		module: path.Base(group.BasePath()),
+	}
+}
+
+// Handle 注册需要 code 权限的路由
+func (g *Guarded) Handle(method, relativePath, code string, handlers ...gin.HandlerFunc) {
+	fullPath := g.group.BasePath()
+	if relativePath != "" {
+		fullPath = path.Join(fullPath, relativePath)
+	}
+	permissionRoutes = append(permissionRoutes, permission.Route{
+		Method: method,
+		Path:   fullPath,
+		Code:   code,
+		Module: g.module,
+	})
+
+	g.group.Handle(method, relativePath, append([]gin.HandlerFunc{requirePermission(code, g.module)}, handlers...)...)
+}
+
+func (g *Guarded) GET(relativePath, code string, handlers ...gin.HandlerFunc) {
+	g.Handle(http.MethodGet, relativePath, code, handlers...)
+}
+
+func (g *Guarded) POST(relativePath, code string, handlers ...gin.HandlerFunc) {
+	g.Handle(http.MethodPost, relativePath, code, handlers...)
+}
+
+func (g *Guarded) PUT(relativePath, code string, handlers ...gin.HandlerFunc) {
+	g.Handle(http.MethodPut, relativePath, code, handlers...)
+}
+
+func (g *Guarded) DELETE(relativePath, code string, handlers ...gin.HandlerFunc) {
+	g.Handle(http.MethodDelete, relativePath, code, handlers...)
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"go-tpl/logic"
+	"go-tpl/logic/permission"
+	"go-tpl/logic/shared"
+	"maps"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// resourceLoaders 按模块加载路由 :id 指向的目标资源，供访问条件中的 resource 使用，查询受调用者数据范围限制
+var resourceLoaders = map[string]func(ctx context.Context, id uint) (any, error){
+	"user": func(ctx context.Context, id uint) (any, error) {
+		return logic.Svc.User.Get(ctx, id)
+	},
+	"role": func(ctx context.Context, id uint) (any, error) {
+		return logic.Svc.Role.Get(ctx, id)
+	},
+	"permission": func(ctx context.Context, id uint) (any, error) {
+		return logic.Svc.Permission.Get(ctx, id)
+	},
+	"dept": func(ctx context.Context, id uint) (any, error) {
+		return logic.Svc.Dept.Get(ctx, id)
+	},
+}
+
+// resourceLoader 返回请求目标资源的加载函数：包含路由参数（数字参数转换为数字），
+// 模块支持按 ID 加载时合并资源记录的字段；资源不存在时只包含路由参数
+func resourceLoader(c *gin.Context, module string) func(ctx context.Context) (map[string]any, error) {
+	return func(ctx context.Context) (map[string]any, error) {
+		resource := make(map[string]any, len(c.Params))
+		for _, param := range c.Params {
+			if n, err := strconv.ParseUint(param.Value, 10, 32); err == nil {
+				resource[param.Key] = n
+			} else {
+				resource[param.Key] = param.Value
+			}
+		}
+
+		load, ok := resourceLoaders[module]
+		id, hasID := resource["id"].(uint64)
+		if !ok || !hasID {
+			return resource, nil
+		}
+
+		record, err := load(ctx, uint(id))
+		if err != nil {
+			if errors.Is(err, shared.ErrRecordNotFound) {
+				return resource, nil
+			}
+			return nil, err
+		}
+		fields, err := permission.ToAttributes(record)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(resource, fields)
+		return resource, nil
+	}
+}
@@ -0,0 +1,80 @@
+package apikey
+
+import (
+	"go-tpl/infra/logger"
+	"go-tpl/logic"
+	"go-tpl/logic/shared"
+	"go-tpl/web/base"
+	"go-tpl/web/middleware"
+	"go-tpl/web/types"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// List 获取当前用户的 API Key
+func List(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		base.FailWithError(c, shared.ErrNoToken)
+		return
+	}
+
+	keys, err := logic.Svc.APIKey.List(c, userID)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OKWithData(c, keys)
+}
+
+// Create 创建 API Key，明文仅在响应中返回一次
+func Create(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		base.FailWithError(c, shared.ErrNoToken)
+		return
+	}
+
+	var req types.CreateAPIKeyReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	key, err := logic.Svc.APIKey.Create(c, userID, req)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OKWithData(c, key)
+}
+
+// Delete 吊销 API Key
+func Delete(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		base.FailWithError(c, shared.ErrNoToken)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	if err = logic.Svc.APIKey.Delete(c, userID, uint(id)); err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OK(c)
+}
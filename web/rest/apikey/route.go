@@ -0,0 +1,16 @@
+package apikey
+
+import (
+	"go-tpl/web/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Register(router *gin.RouterGroup) {
+	r := router.Group("/api-keys").Use(middleware.TokenAuth(), middleware.RequireSession())
+	{
+		r.GET("", List)          // 获取当前用户的 API Key
+		r.POST("", Create)       // 创建 API Key
+		r.DELETE("/:id", Delete) // 吊销 API Key
+	}
+}
@@ -0,0 +1,105 @@
+package dept
+
+import (
+	"go-tpl/infra/logger"
+	"go-tpl/logic"
+	"go-tpl/logic/shared"
+	"go-tpl/web/base"
+	"go-tpl/web/types"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Tree 获取部门树
+func Tree(c *gin.Context) {
+	tree, err := logic.Svc.Dept.Tree(c)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OKWithData(c, tree)
+}
+
+// Get 获取单个部门
+func Get(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	dept, err := logic.Svc.Dept.Get(c, uint(id))
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OKWithData(c, dept)
+}
+
+// Create 创建部门
+func Create(c *gin.Context) {
+	var req types.CreateDeptReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	dept, err := logic.Svc.Dept.Create(c, req)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OKWithData(c, dept)
+}
+
+// Update 更新部门
+func Update(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	var req types.UpdateDeptReq
+	if err = c.ShouldBindJSON(&req); err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	if err = logic.Svc.Dept.Update(c, uint(id), req); err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OK(c)
+}
+
+// Delete 删除部门
+func Delete(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	if err = logic.Svc.Dept.Delete(c, uint(id)); err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OK(c)
+}
@@ -0,0 +1,18 @@
+package dept
+
+import (
+	"go-tpl/web/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Register(router *gin.RouterGroup) {
+	r := middleware.Guard(router.Group("/dept", middleware.TokenAuth()))
+	{
+		r.GET("", "dept:read", Tree)            // 获取部门树
+		r.GET("/:id", "dept:read", Get)         // 获取单个部门
+		r.POST("", "dept:create", Create)       // 创建部门
+		r.PUT("/:id", "dept:update", Update)    // 更新部门
+		r.DELETE("/:id", "dept:delete", Delete) // 删除部门
+	}
+}
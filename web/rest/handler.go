@@ -1,11 +1,14 @@
 package rest
 
 import (
+	"go-tpl/infra/jwt"
 	"go-tpl/infra/logger"
 	"go-tpl/logic"
 	"go-tpl/logic/shared"
 	"go-tpl/web/base"
+	"go-tpl/web/middleware"
 	"go-tpl/web/types"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
@@ -28,12 +31,42 @@ func Register(c *gin.Context) {
 	}
 
 	// 使用auth service注册
-	tokenPair, err := logic.Svc.Auth.Register(c, req)
+	resp, err := logic.Svc.Auth.Register(c, req, clientInfo(c))
 	if err != nil {
 		base.FailWithError(c, err)
 		return
 	}
-	base.OKWithData(c, tokenPair)
+	base.OKWithData(c, resp)
+}
+
+// VerifyEmail 验证邮箱
+func VerifyEmail(c *gin.Context) {
+	var req types.VerifyEmailReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	if err := logic.Svc.Auth.VerifyEmail(c, req); err != nil {
+		base.FailWithError(c, err)
+		return
+	}
+	base.OK(c)
+}
+
+// ResendVerification 重新发送验证邮件
+func ResendVerification(c *gin.Context) {
+	var req types.ResendVerificationReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	if err := logic.Svc.Auth.ResendVerification(c, req); err != nil {
+		base.FailWithError(c, err)
+		return
+	}
+	base.OK(c)
 }
 
 func Login(c *gin.Context) {
@@ -44,7 +77,24 @@ func Login(c *gin.Context) {
 	}
 
 	// 使用auth service登录
-	tokenPair, err := logic.Svc.Auth.Login(c, req)
+	tokenPair, err := logic.Svc.Auth.Login(c, req, clientInfo(c))
+	if err != nil {
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OKWithData(c, tokenPair)
+}
+
+// LoginTwoFactor 两步验证登录
+func LoginTwoFactor(c *gin.Context) {
+	var req types.LoginTwoFactorReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	tokenPair, err := logic.Svc.Auth.LoginTwoFactor(c, req, clientInfo(c))
 	if err != nil {
 		base.FailWithError(c, err)
 		return
@@ -53,6 +103,87 @@ func Login(c *gin.Context) {
 	base.OKWithData(c, tokenPair)
 }
 
+// OIDCProviders 获取可用的第三方登录方式
+func OIDCProviders(c *gin.Context) {
+	base.OKWithData(c, logic.Svc.Auth.OIDCProviders())
+}
+
+// oidcBindingCookie 第三方登录的浏览器绑定 Cookie，回调时须与 state 对应
+const oidcBindingCookie = "oidc_binding"
+
+// OIDCAuthorize 发起第三方登录，返回 IdP 授权地址，并将授权请求绑定到当前浏览器
+func OIDCAuthorize(c *gin.Context) {
+	resp, err := logic.Svc.Auth.OIDCAuthorize(c, c.Param("provider"))
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	setOIDCBindingCookie(c, resp.Binding, 600)
+	base.OKWithData(c, resp)
+}
+
+// OIDCCallback 第三方登录回调，使用授权码换取 token 对
+func OIDCCallback(c *gin.Context) {
+	var req types.OIDCCallbackReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	binding, _ := c.Cookie(oidcBindingCookie)
+	// 绑定值随 state 一次性使用，无论成功与否均清除
+	setOIDCBindingCookie(c, "", -1)
+
+	resp, err := logic.Svc.Auth.OIDCCallback(c, c.Param("provider"), req, binding, clientInfo(c))
+	if err != nil {
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OKWithData(c, resp)
+}
+
+// setOIDCBindingCookie 写入仅限第三方登录接口使用的 HttpOnly、SameSite=Lax Cookie，maxAge 小于 0 时删除
+func setOIDCBindingCookie(c *gin.Context, value string, maxAge int) {
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcBindingCookie, value, maxAge, "/api/oidc", "", secure, true)
+}
+
+// ForgotPassword 忘记密码，发送重置邮件
+func ForgotPassword(c *gin.Context) {
+	var req types.ForgotPasswordReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	if err := logic.Svc.Auth.ForgotPassword(c, req); err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+	base.OK(c)
+}
+
+// ResetPassword 使用重置 token 设置新密码
+func ResetPassword(c *gin.Context) {
+	var req types.ResetPasswordReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	if err := logic.Svc.Auth.ResetPassword(c, req); err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+	base.OK(c)
+}
+
 // RefreshToken 刷新 token
 func RefreshToken(c *gin.Context) {
 	var req struct {
@@ -64,10 +195,42 @@ func RefreshToken(c *gin.Context) {
 	}
 
 	// 使用auth service刷新token
-	tokenPair, err := logic.Svc.Auth.RefreshToken(c, req.RefreshToken)
+	tokenPair, err := logic.Svc.Auth.RefreshToken(c, req.RefreshToken, clientInfo(c))
 	if err != nil {
 		base.FailWithError(c, err)
 		return
 	}
 	base.OKWithData(c, tokenPair)
 }
+
+// Logout 登出
+func Logout(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		base.FailWithError(c, shared.ErrNoToken)
+		return
+	}
+
+	if err := logic.Svc.Auth.Logout(c, claims); err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+	base.OK(c)
+}
+
+// JWKS 公布 JWT 验证公钥
+func JWKS(c *gin.Context) {
+	jwks, err := jwt.PublicJWKS()
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, jwks)
+}
+
+// clientInfo 提取客户端 IP 和 User-Agent
+func clientInfo(c *gin.Context) types.ClientInfo {
+	return types.ClientInfo{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+}
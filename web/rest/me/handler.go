@@ -0,0 +1,133 @@
+package me
+
+import (
+	"go-tpl/infra/logger"
+	"go-tpl/logic"
+	"go-tpl/logic/shared"
+	"go-tpl/web/base"
+	"go-tpl/web/middleware"
+	"go-tpl/web/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Profile 获取个人资料
+func Profile(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		base.FailWithError(c, shared.ErrNoToken)
+		return
+	}
+
+	user, err := logic.Svc.User.Get(c, userID)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OKWithData(c, user)
+}
+
+// UpdateProfile 更新个人资料
+func UpdateProfile(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		base.FailWithError(c, shared.ErrNoToken)
+		return
+	}
+
+	var req types.UpdateProfileReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	resp, err := logic.Svc.Auth.UpdateProfile(c, userID, req)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OKWithData(c, resp)
+}
+
+// ChangePassword 修改密码，成功后需重新登录
+func ChangePassword(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		base.FailWithError(c, shared.ErrNoToken)
+		return
+	}
+
+	var req types.ChangePasswordReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	if err := logic.Svc.User.ChangePassword(c, userID, req); err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OK(c)
+}
+
+// Roles 获取自己的角色
+func Roles(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		base.FailWithError(c, shared.ErrNoToken)
+		return
+	}
+
+	roles, err := logic.Svc.Role.ListUserRoles(c, userID)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OKWithData(c, roles)
+}
+
+// Permissions 获取自己的有效权限代码
+func Permissions(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		base.FailWithError(c, shared.ErrNoToken)
+		return
+	}
+
+	codes, err := logic.Svc.Permission.GetUserPermissions(c, userID)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OKWithData(c, codes)
+}
+
+// PermissionTree 获取按自己的有效权限裁剪的权限树，用于渲染导航菜单
+func PermissionTree(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		base.FailWithError(c, shared.ErrNoToken)
+		return
+	}
+
+	tree, err := logic.Svc.Permission.UserTree(c, userID)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OKWithData(c, tree)
+}
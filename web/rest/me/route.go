@@ -0,0 +1,19 @@
+package me
+
+import (
+	"go-tpl/web/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Register(router *gin.RouterGroup) {
+	r := router.Group("/me").Use(middleware.TokenAuth())
+	{
+		r.GET("", Profile)                                              // 获取个人资料
+		r.PUT("", middleware.RequireSession(), UpdateProfile)           // 更新个人资料
+		r.PUT("/password", middleware.RequireSession(), ChangePassword) // 修改密码
+		r.GET("/roles", Roles)                                          // 获取自己的角色
+		r.GET("/permissions", Permissions)                              // 获取自己的有效权限代码
+		r.GET("/permissions/tree", PermissionTree)                      // 获取自己的权限树（导航菜单）
+	}
+}
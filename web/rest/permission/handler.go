@@ -5,6 +5,7 @@ import (
 	"go-tpl/logic"
 	"go-tpl/logic/shared"
 	"go-tpl/web/base"
+	"go-tpl/web/middleware"
 	"go-tpl/web/types"
 	"strconv"
 
@@ -151,6 +152,30 @@ func GetModules(c *gin.Context) {
 	base.OKWithData(c, modules)
 }
 
+// Tree 获取完整的权限树
+func Tree(c *gin.Context) {
+	tree, err := logic.Svc.Permission.Tree(c)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OKWithData(c, tree)
+}
+
+// GetRouteMap 获取路由与权限代码的对应关系，以及未被任何路由使用的权限
+func GetRouteMap(c *gin.Context) {
+	data, err := logic.Svc.Permission.GetRouteMap(c, middleware.PermissionRoutes())
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OKWithData(c, data)
+}
+
 // GetPermissionRoles 获取权限角色列表
 func GetPermissionRoles(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -169,3 +194,22 @@ func GetPermissionRoles(c *gin.Context) {
 
 	base.OKWithData(c, roleIds)
 }
+
+// TestCondition 使用示例属性测试访问条件表达式
+func TestCondition(c *gin.Context) {
+	var req types.TestConditionReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	result, err := logic.Svc.Permission.TestCondition(c, req)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OKWithData(c, result)
+}
@@ -1,17 +1,26 @@
 package permission
 
-import "github.com/gin-gonic/gin"
+import (
+	"go-tpl/web/middleware"
+
+	"github.com/gin-gonic/gin"
+)
 
 func Register(router *gin.RouterGroup) {
-	r := router.Group("/permission")
+	r := middleware.Guard(router.Group("/permission", middleware.TokenAuth()))
 	{
-		r.POST("/list", List)                        // 获取权限列表
-		r.GET("/:id", Get)                            // 获取单个权限
-		r.POST("", Create)                            // 创建权限
-		r.PUT("/:id", Update)                         // 更新权限
-		r.DELETE("/:id", Delete)                      // 删除权限
-		r.PUT("/:id/status", UpdateStatus)            // 更新权限状态
-		r.GET("/modules", GetModules)                 // 获取所有模块
-		r.GET("/:id/roles", GetPermissionRoles)       // 获取权限角色
+		r.POST("/list", "permission:read", List)                   // 获取权限列表
+		r.GET("/:id", "permission:read", Get)                      // 获取单个权限
+		r.POST("", "permission:create", Create)                    // 创建权限
+		r.PUT("/:id", "permission:update", Update)                 // 更新权限
+		r.DELETE("/:id", "permission:delete", Delete)              // 删除权限
+		r.PUT("/:id/status", "permission:update", UpdateStatus)    // 更新权限状态
+		r.GET("/modules", "permission:read", GetModules)           // 获取所有模块
+		r.GET("/tree", "permission:read", Tree)                    // 获取权限树
+		r.GET("/routes", "permission:read", GetRouteMap)           // 获取路由与权限对应关系
+		r.GET("/:id/roles", "permission:read", GetPermissionRoles) // 获取权限角色
+
+		// 访问条件
+		r.POST("/condition/test", "permission:read", TestCondition) // 使用示例属性测试条件表达式
 	}
 }
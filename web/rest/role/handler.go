@@ -148,7 +148,9 @@ func GetRolePermissions(c *gin.Context) {
 		return
 	}
 
-	permissionIds, err := logic.Svc.Role.GetRolePermissions(c, uint(id))
+	// inherited=true 时包含从祖先角色继承的权限
+	inherited, _ := strconv.ParseBool(c.Query("inherited"))
+	permissionIds, err := logic.Svc.Role.GetRolePermissions(c, uint(id), inherited)
 	if err != nil {
 		logger.Errw(c, err)
 		base.FailWithError(c, err)
@@ -174,7 +176,85 @@ func AssignPermissions(c *gin.Context) {
 		return
 	}
 
-	err = logic.Svc.Role.AssignPermissions(c, uint(id), req.PermissionIds)
+	result, err := logic.Svc.Role.AssignPermissions(c, uint(id), req.PermissionIds)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OKWithData(c, result)
+}
+
+// AddPermission 为角色增加单个权限
+func AddPermission(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	permissionID, err := strconv.ParseUint(c.Param("permissionId"), 10, 32)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	result, err := logic.Svc.Role.AddPermission(c, uint(id), uint(permissionID))
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OKWithData(c, result)
+}
+
+// RemovePermission 移除角色的单个权限
+func RemovePermission(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	permissionID, err := strconv.ParseUint(c.Param("permissionId"), 10, 32)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	result, err := logic.Svc.Role.RemovePermission(c, uint(id), uint(permissionID))
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OKWithData(c, result)
+}
+
+// SetParents 设置上级角色，角色继承上级角色的全部权限
+func SetParents(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	var req types.SetRoleParentsReq
+	if err = c.ShouldBindJSON(&req); err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	err = logic.Svc.Role.SetParents(c, uint(id), req.ParentIds)
 	if err != nil {
 		logger.Errw(c, err)
 		base.FailWithError(c, err)
@@ -202,3 +282,79 @@ func GetRoleUsers(c *gin.Context) {
 
 	base.OKWithData(c, userIds)
 }
+
+// SetDataScope 设置角色数据范围
+func SetDataScope(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	var req types.SetRoleDataScopeReq
+	if err = c.ShouldBindJSON(&req); err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	if err = logic.Svc.Role.SetDataScope(c, uint(id), req); err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OK(c)
+}
+
+// GetPermissionConditions 获取角色附带访问条件的权限关联
+func GetPermissionConditions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	links, err := logic.Svc.Role.GetPermissionConditions(c, uint(id))
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OKWithData(c, links)
+}
+
+// SetPermissionCondition 设置角色权限关联的访问条件
+func SetPermissionCondition(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	permissionID, err := strconv.ParseUint(c.Param("permissionId"), 10, 32)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	var req types.SetPermissionConditionReq
+	if err = c.ShouldBindJSON(&req); err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	if err = logic.Svc.Role.SetPermissionCondition(c, uint(id), uint(permissionID), req.Condition); err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OK(c)
+}
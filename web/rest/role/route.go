@@ -7,16 +7,26 @@ import (
 )
 
 func Register(router *gin.RouterGroup) {
-	r := router.Group("/role").Use(middleware.TokenAuth())
+	r := middleware.Guard(router.Group("/role", middleware.TokenAuth()))
 	{
-		r.POST("/list", List)                         // 获取角色列表
-		r.GET("/:id", Get)                            // 获取单个角色
-		r.POST("", Create)                            // 创建角色
-		r.PUT("/:id", Update)                         // 更新角色
-		r.DELETE("/:id", Delete)                      // 删除角色
-		r.PUT("/:id/status", UpdateStatus)            // 更新角色状态
-		r.GET("/:id/permissions", GetRolePermissions) // 获取角色权限
-		r.PUT("/:id/permissions", AssignPermissions)  // 分配角色权限
-		r.GET("/:id/users", GetRoleUsers)             // 获取角色用户
+		r.POST("/list", "role:read", List)                          // 获取角色列表
+		r.GET("/:id", "role:read", Get)                             // 获取单个角色
+		r.POST("", "role:create", Create)                           // 创建角色
+		r.PUT("/:id", "role:update", Update)                        // 更新角色
+		r.DELETE("/:id", "role:delete", Delete)                     // 删除角色
+		r.PUT("/:id/status", "role:update", UpdateStatus)           // 更新角色状态
+		r.GET("/:id/permissions", "role:read", GetRolePermissions)  // 获取角色权限
+		r.PUT("/:id/permissions", "role:update", AssignPermissions) // 分配角色权限
+		r.PUT("/:id/parents", "role:update", SetParents)            // 设置上级角色
+		r.PUT("/:id/data-scope", "role:update", SetDataScope)       // 设置角色数据范围
+		r.GET("/:id/users", "role:read", GetRoleUsers)              // 获取角色用户
+
+		// 增量分配角色权限，不影响其他已分配的权限
+		r.POST("/:id/permissions/:permissionId", "role:update", AddPermission)      // 增加角色权限
+		r.DELETE("/:id/permissions/:permissionId", "role:update", RemovePermission) // 移除角色权限
+
+		// 角色权限关联的访问条件
+		r.GET("/:id/permissions/conditions", "role:read", GetPermissionConditions)               // 获取附带条件的角色权限
+		r.PUT("/:id/permissions/:permissionId/condition", "role:update", SetPermissionCondition) // 设置角色权限条件
 	}
 }
@@ -0,0 +1,54 @@
+package session
+
+import (
+	"go-tpl/infra/logger"
+	"go-tpl/logic"
+	"go-tpl/logic/shared"
+	"go-tpl/web/base"
+	"go-tpl/web/middleware"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// List 获取当前用户的登录会话
+func List(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		base.FailWithError(c, shared.ErrNoToken)
+		return
+	}
+
+	sessions, err := logic.Svc.User.ListSessions(c, claims.UserID, claims.FamilyID)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OKWithData(c, sessions)
+}
+
+// Revoke 吊销当前用户的指定会话
+func Revoke(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		base.FailWithError(c, shared.ErrNoToken)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	if err = logic.Svc.User.RevokeSession(c, userID, uint(id)); err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OK(c)
+}
@@ -0,0 +1,15 @@
+package session
+
+import (
+	"go-tpl/web/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Register(router *gin.RouterGroup) {
+	r := router.Group("/sessions").Use(middleware.TokenAuth(), middleware.RequireSession())
+	{
+		r.GET("", List)          // 获取当前用户的登录会话
+		r.DELETE("/:id", Revoke) // 吊销当前用户的指定会话
+	}
+}
@@ -0,0 +1,105 @@
+package twofactor
+
+import (
+	"go-tpl/infra/logger"
+	"go-tpl/logic"
+	"go-tpl/logic/shared"
+	"go-tpl/web/base"
+	"go-tpl/web/middleware"
+	"go-tpl/web/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Enroll 生成待确认的 TOTP 密钥
+func Enroll(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		base.FailWithError(c, shared.ErrNoToken)
+		return
+	}
+
+	enrollment, err := logic.Svc.User.EnrollTwoFactor(c, userID)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OKWithData(c, enrollment)
+}
+
+// Confirm 校验验证码并开启两步验证，返回恢复码
+func Confirm(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		base.FailWithError(c, shared.ErrNoToken)
+		return
+	}
+
+	var req types.TwoFactorCodeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	codes, err := logic.Svc.User.ConfirmTwoFactor(c, userID, req.Code)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OKWithData(c, codes)
+}
+
+// Disable 关闭两步验证
+func Disable(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		base.FailWithError(c, shared.ErrNoToken)
+		return
+	}
+
+	var req types.TwoFactorCodeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	err := logic.Svc.User.DisableTwoFactor(c, userID, req.Code)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OK(c)
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码
+func RegenerateRecoveryCodes(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		base.FailWithError(c, shared.ErrNoToken)
+		return
+	}
+
+	var req types.TwoFactorCodeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	codes, err := logic.Svc.User.RegenerateRecoveryCodes(c, userID, req.Code)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OKWithData(c, codes)
+}
@@ -0,0 +1,17 @@
+package twofactor
+
+import (
+	"go-tpl/web/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Register(router *gin.RouterGroup) {
+	r := router.Group("/2fa").Use(middleware.TokenAuth(), middleware.RequireSession())
+	{
+		r.POST("/enroll", Enroll)                          // 生成 TOTP 密钥
+		r.POST("/confirm", Confirm)                        // 确认绑定并开启两步验证
+		r.POST("/disable", Disable)                        // 关闭两步验证
+		r.POST("/recovery-codes", RegenerateRecoveryCodes) // 重新生成恢复码
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"go-tpl/logic"
 	"go-tpl/logic/shared"
 	"go-tpl/web/base"
+	"go-tpl/web/middleware"
 	"go-tpl/web/types"
 	"strconv"
 
@@ -136,6 +137,111 @@ func UpdateStatus(c *gin.Context) {
 	base.OK(c)
 }
 
+// Unlock 解锁因登录失败被锁定的用户账户
+func Unlock(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	err = logic.Svc.User.Unlock(c, uint(id))
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OK(c)
+}
+
+// UnlockIP 解除因登录失败被锁定的 IP
+func UnlockIP(c *gin.Context) {
+	var req types.UnlockIPReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	if err := logic.Svc.User.UnlockIP(c, req.IP); err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OK(c)
+}
+
+// ListSessions 获取用户登录会话
+func ListSessions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	sessions, err := logic.Svc.User.ListSessions(c, uint(id), "")
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OKWithData(c, sessions)
+}
+
+// RevokeSession 吊销用户指定会话
+func RevokeSession(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	sessionID, err := strconv.ParseUint(c.Param("sessionId"), 10, 32)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	if err = logic.Svc.User.RevokeSession(c, uint(id), uint(sessionID)); err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OK(c)
+}
+
+// RevokeAllSessions 吊销用户全部会话
+func RevokeAllSessions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	if _, err = logic.Svc.User.Get(c, uint(id)); err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	if err = logic.Svc.User.RevokeAllSessions(c, uint(id)); err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OK(c)
+}
+
 // GetUserRoles 获取用户角色列表
 func GetUserRoles(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -145,7 +251,9 @@ func GetUserRoles(c *gin.Context) {
 		return
 	}
 
-	roleIds, err := logic.Svc.User.GetUserRoles(c, uint(id))
+	// 默认仅返回当前生效的角色，include_inactive=true 时包含未生效和已过期的角色
+	includeInactive, _ := strconv.ParseBool(c.Query("include_inactive"))
+	roleIds, err := logic.Svc.User.GetUserRoles(c, uint(id), includeInactive)
 	if err != nil {
 		logger.Errw(c, err)
 		base.FailWithError(c, err)
@@ -155,6 +263,32 @@ func GetUserRoles(c *gin.Context) {
 	base.OKWithData(c, roleIds)
 }
 
+// GetUserPermissions 获取用户有效权限代码
+func GetUserPermissions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	// 确认用户存在且在数据范围内
+	if _, err = logic.Svc.User.Get(c, uint(id)); err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	codes, err := logic.Svc.Permission.GetUserPermissions(c, uint(id))
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OKWithData(c, codes)
+}
+
 // AssignRoles 为用户分配角色
 func AssignRoles(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -171,12 +305,106 @@ func AssignRoles(c *gin.Context) {
 		return
 	}
 
-	err = logic.Svc.User.AssignRoles(c, uint(id), req.RoleIds)
+	result, err := logic.Svc.User.AssignRoles(c, uint(id), req)
 	if err != nil {
 		logger.Errw(c, err)
 		base.FailWithError(c, err)
 		return
 	}
 
-	base.OK(c)
+	base.OKWithData(c, result)
+}
+
+// AddRole 为用户增加单个角色，请求体可省略
+func AddRole(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	roleID, err := strconv.ParseUint(c.Param("roleId"), 10, 32)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	var req types.AddUserRoleReq
+	if c.Request.ContentLength != 0 {
+		if err = c.ShouldBindJSON(&req); err != nil {
+			logger.Errw(c, err)
+			base.FailWithError(c, shared.ErrInvalidParam)
+			return
+		}
+	}
+
+	result, err := logic.Svc.User.AddRole(c, uint(id), uint(roleID), req)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OKWithData(c, result)
+}
+
+// RemoveRole 移除用户的单个角色
+func RemoveRole(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	roleID, err := strconv.ParseUint(c.Param("roleId"), 10, 32)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	result, err := logic.Svc.User.RemoveRole(c, uint(id), uint(roleID))
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OKWithData(c, result)
+}
+
+// Impersonate 模拟登录，返回以该用户身份访问的短期 access token
+func Impersonate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	var req types.ImpersonateReq
+	if err = c.ShouldBindJSON(&req); err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, shared.ErrInvalidParam)
+		return
+	}
+
+	actorID, ok := middleware.GetUserID(c)
+	if !ok {
+		base.FailWithError(c, shared.ErrNoToken)
+		return
+	}
+
+	client := types.ClientInfo{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+	token, err := logic.Svc.Auth.Impersonate(c, actorID, uint(id), req, client)
+	if err != nil {
+		logger.Errw(c, err)
+		base.FailWithError(c, err)
+		return
+	}
+
+	base.OKWithData(c, token)
 }
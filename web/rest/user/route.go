@@ -7,15 +7,26 @@ import (
 )
 
 func Register(router *gin.RouterGroup) {
-	r := router.Group("/user").Use(middleware.TokenAuth())
+	r := middleware.Guard(router.Group("/user", middleware.TokenAuth()))
 	{
-		r.POST("/list", List)              // 获取用户列表
-		r.GET("/:id", Get)                 // 获取单个用户
-		r.POST("", Create)                 // 创建用户
-		r.PUT("/:id", Update)              // 更新用户
-		r.DELETE("/:id", Delete)           // 删除用户
-		r.PUT("/:id/status", UpdateStatus) // 更新用户状态
-		r.GET("/:id/roles", GetUserRoles)  // 获取用户角色
-		r.PUT("/:id/roles", AssignRoles)   // 分配用户角色
+		r.POST("/list", "user:read", List)                                 // 获取用户列表
+		r.GET("/:id", "user:read", Get)                                    // 获取单个用户
+		r.POST("", "user:create", Create)                                  // 创建用户
+		r.PUT("/:id", "user:update", Update)                               // 更新用户
+		r.DELETE("/:id", "user:delete", Delete)                            // 删除用户
+		r.PUT("/:id/status", "user:update", UpdateStatus)                  // 更新用户状态
+		r.PUT("/:id/unlock", "user:update", Unlock)                        // 解锁用户账户
+		r.PUT("/unlock-ip", "user:update", UnlockIP)                       // 解除 IP 登录锁定
+		r.GET("/:id/sessions", "user:read", ListSessions)                  // 获取用户登录会话
+		r.DELETE("/:id/sessions", "user:update", RevokeAllSessions)        // 吊销用户全部会话
+		r.DELETE("/:id/sessions/:sessionId", "user:update", RevokeSession) // 吊销用户指定会话
+		r.GET("/:id/roles", "user:read", GetUserRoles)                     // 获取用户角色
+		r.GET("/:id/permissions", "user:read", GetUserPermissions)         // 获取用户有效权限
+		r.PUT("/:id/roles", "role:update", AssignRoles)                    // 分配用户角色（涉及授权，需角色管理权限）
+		r.POST("/:id/roles/:roleId", "role:update", AddRole)               // 增加用户角色
+		r.DELETE("/:id/roles/:roleId", "role:update", RemoveRole)          // 移除用户角色
+
+		// 模拟登录，签发以该用户身份访问的短期 token
+		r.POST("/:id/impersonate", "user:impersonate", middleware.RequireSession(), Impersonate)
 	}
 }
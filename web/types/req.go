@@ -1,6 +1,9 @@
 package types
 
-import "go-tpl/logic/shared"
+import (
+	"go-tpl/logic/shared"
+	"time"
+)
 
 // 用户相关请求类型
 type UserQueryReq struct {
@@ -13,7 +16,8 @@ type UserQueryReq struct {
 type CreateUserReq struct {
 	Username string `json:"username" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"` // 规则由密码策略校验
+	DeptID   uint   `json:"dept_id"`
 }
 
 type UpdateUserReq struct {
@@ -21,6 +25,19 @@ type UpdateUserReq struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 	Status   *int8  `json:"status"`
+	DeptID   *uint  `json:"dept_id"` // 0 表示移出部门
+}
+
+// UpdateProfileReq 更新个人资料请求
+type UpdateProfileReq struct {
+	Username string `json:"username"`
+	Email    string `json:"email" binding:"omitempty,email"`
+}
+
+// ChangePasswordReq 修改密码请求
+type ChangePasswordReq struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"` // 规则由密码策略校验
 }
 
 // 角色相关请求类型
@@ -45,6 +62,17 @@ type AssignRolePermissionsReq struct {
 	PermissionIds []uint `json:"permission_ids" binding:"required"`
 }
 
+// SetRoleParentsReq 设置上级角色请求，空列表表示取消继承
+type SetRoleParentsReq struct {
+	ParentIds []uint `json:"parent_ids" binding:"required"`
+}
+
+// SetRoleDataScopeReq 设置角色数据范围请求
+type SetRoleDataScopeReq struct {
+	DataScope int8   `json:"data_scope" binding:"required,min=1,max=5"` // 1-全部, 2-自定义部门, 3-本部门, 4-本部门及下级, 5-仅本人
+	DeptIds   []uint `json:"dept_ids"`                                  // 自定义部门，仅 data_scope 为 2 时有效
+}
+
 // 权限相关请求类型
 type PermissionQueryReq struct {
 	shared.Pagination
@@ -55,23 +83,65 @@ type PermissionQueryReq struct {
 }
 
 type CreatePermissionReq struct {
+	ParentID    uint   `json:"parent_id"` // 0 表示顶级权限
 	Code        string `json:"code" binding:"required"`
 	Name        string `json:"name" binding:"required"`
 	Description string `json:"description"`
 	Module      string `json:"module" binding:"required"`
+	Type        string `json:"type" binding:"omitempty,oneof=menu button api"` // 为空时默认 api
+	Path        string `json:"path" binding:"max=255"`
+	Icon        string `json:"icon" binding:"max=100"`
+	Sort        int    `json:"sort"`
+	Condition   string `json:"condition" binding:"max=1024"` // 访问条件表达式，为空表示无条件
 }
 
 type UpdatePermissionReq struct {
+	ParentID    *uint   `json:"parent_id"`
 	Code        string `json:"code"`
 	Name        string `json:"name"`
 	Description *string `json:"description"`
 	Module      *string `json:"module"`
+	Type        *string `json:"type" binding:"omitempty,oneof=menu button api"`
+	Path        *string `json:"path" binding:"omitempty,max=255"`
+	Icon        *string `json:"icon" binding:"omitempty,max=100"`
+	Sort        *int    `json:"sort"`
 	Status      *int8   `json:"status"`
+	Condition   *string `json:"condition" binding:"omitempty,max=1024"` // 空字符串表示清除条件
+}
+
+// 部门相关请求类型
+type CreateDeptReq struct {
+	ParentID uint   `json:"parent_id"` // 0 表示顶级部门
+	Name     string `json:"name" binding:"required,max=50"`
+	Sort     int    `json:"sort"`
+}
+
+type UpdateDeptReq struct {
+	ParentID *uint  `json:"parent_id"`
+	Name     string `json:"name" binding:"max=50"`
+	Sort     *int   `json:"sort"`
 }
 
 // 用户角色相关请求类型
+// AssignUserRolesReq 分配用户角色请求，覆盖用户现有角色，两者均为空列表表示清空角色
 type AssignUserRolesReq struct {
-	RoleIds []uint `json:"role_ids" binding:"required"`
+	RoleIds []uint         `json:"role_ids"`              // 长期有效的角色
+	Grants  []RoleGrantReq `json:"grants" binding:"dive"` // 带有效期的角色
+}
+
+// AddUserRoleReq 为用户增加单个角色请求，可省略请求体
+type AddUserRoleReq struct {
+	StartsAt  *time.Time `json:"starts_at"`  // 为空表示立即生效
+	ExpiresAt *time.Time `json:"expires_at"` // 为空表示永不过期
+	Reason    string     `json:"reason" binding:"max=255"`
+}
+
+// RoleGrantReq 带有效期的角色授予
+type RoleGrantReq struct {
+	RoleID    uint       `json:"role_id" binding:"required"`
+	StartsAt  *time.Time `json:"starts_at"`  // 为空表示立即生效
+	ExpiresAt *time.Time `json:"expires_at"` // 为空表示永不过期
+	Reason    string     `json:"reason" binding:"max=255"`
 }
 
 // 状态更新请求类型
@@ -79,6 +149,11 @@ type UpdateStatusReq struct {
 	Status int8 `json:"status" binding:"required"`
 }
 
+// UnlockIPReq 解除 IP 登录锁定请求
+type UnlockIPReq struct {
+	IP string `json:"ip" binding:"required,ip"`
+}
+
 // 登录注册请求类型
 type LoginReq struct {
 	Username string `json:"username" binding:"required"`
@@ -88,5 +163,73 @@ type LoginReq struct {
 type RegisterReq struct {
 	Username string `json:"username" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"` // 规则由密码策略校验
+}
+
+// 两步验证相关请求类型
+type TwoFactorCodeReq struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type LoginTwoFactorReq struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"` // TOTP 验证码或恢复码
+}
+
+// 密码重置相关请求类型
+type ForgotPasswordReq struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordReq struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"` // 规则由密码策略校验
+}
+
+// VerifyEmailReq 邮箱验证请求
+type VerifyEmailReq struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ResendVerificationReq 重发验证邮件请求
+type ResendVerificationReq struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ClientInfo 客户端信息，用于登录保护和会话记录
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// CreateAPIKeyReq 创建 API Key 请求
+type CreateAPIKeyReq struct {
+	Name        string     `json:"name" binding:"required,max=100"`
+	Permissions []string   `json:"permissions" binding:"required,min=1"` // 须为所属用户权限的子集
+	ExpiresAt   *time.Time `json:"expires_at"`                           // 为空表示永不过期
+}
+
+// OIDCCallbackReq 第三方登录回调请求，参数来自 IdP 重定向到前端回调页面的查询参数
+type OIDCCallbackReq struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+// ImpersonateReq 模拟登录请求，原因写入审计记录
+type ImpersonateReq struct {
+	Reason string `json:"reason" binding:"required,max=255"`
+}
+
+// SetPermissionConditionReq 设置角色权限关联的访问条件请求，空字符串表示清除条件
+type SetPermissionConditionReq struct {
+	Condition string `json:"condition" binding:"max=1024"`
+}
+
+// TestConditionReq 访问条件测试请求，使用示例属性代替真实请求属性
+type TestConditionReq struct {
+	Condition string         `json:"condition" binding:"required,max=1024"`
+	IP        string         `json:"ip"`
+	Time      *time.Time     `json:"time"` // 为空表示当前时间，按服务器时区计算 hour、weekday 等
+	User      map[string]any `json:"user"`
+	Resource  map[string]any `json:"resource"`
 }
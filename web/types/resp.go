@@ -1,7 +1,34 @@
 package types
 
+import "go-tpl/infra/jwt"
+
 type Resp struct {
 	Code int    `json:"code"`
 	Msg  string `json:"msg"`
 	Data any    `json:"data,omitempty"`
 }
+
+// LoginResp 登录响应，开启两步验证的用户返回挑战 token 而非 token 对
+type LoginResp struct {
+	*jwt.TokenPair
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
+}
+
+// RegisterResp 注册响应，需要验证邮箱时不返回 token 对
+type RegisterResp struct {
+	*jwt.TokenPair
+	VerificationRequired bool `json:"verification_required,omitempty"`
+}
+
+// UpdateProfileResp 更新个人资料响应，修改邮箱时需验证新邮箱后才生效
+type UpdateProfileResp struct {
+	VerificationRequired bool `json:"verification_required,omitempty"`
+}
+
+// OIDCAuthorizeResp 第三方登录授权地址，前端跳转到 auth_url 完成登录
+type OIDCAuthorizeResp struct {
+	AuthURL string `json:"auth_url"`
+	State   string `json:"state"`
+	Binding string `json:"-"` // 浏览器绑定值，仅通过 HttpOnly Cookie 下发
+}